@@ -0,0 +1,304 @@
+package common
+
+import (
+	"math/big"
+
+	"github.com/consensys/gnark-crypto/ecc"
+	bls12381 "github.com/consensys/gnark-crypto/ecc/bls12-381"
+	"github.com/consensys/gnark-crypto/ecc/bls12-381/fp"
+	"github.com/consensys/gnark-crypto/ecc/bls12-381/fr"
+)
+
+// batchAffineMinSize is the number of points below which the inversion shared
+// by each step of mulAddG1 costs more than it saves, and the scalar
+// multiplications are done in Jacobian coordinates instead.
+const batchAffineMinSize = 32
+
+var (
+	// glvOmega is the cube root of unity such that ϕ(x, y) = (glvOmega·x, y)
+	// is the multiplication by glvLambda on G1.
+	glvOmega  fp.Element
+	glvLambda big.Int
+	glvBasis  ecc.Lattice
+)
+
+func init() {
+	glvOmega.SetString("4002409555221667392624310435006688643935503118305586438271171395842971157480381377015405980053539358417135540939436")
+	glvLambda.SetString("228988810152649578064853576960394133503", 10)
+	ecc.PrecomputeLattice(fr.Modulus(), &glvLambda, &glvBasis)
+}
+
+// glvScalar is a scalar k split as k1 + k2·glvLambda, in 2-bit windows.
+type glvScalar struct {
+	k          *big.Int
+	neg1, neg2 bool
+	// digits[j] holds the j-th windows of |k2| and |k1| as 4·|k2|_j + |k1|_j.
+	digits []uint8
+}
+
+func newGLVScalar(k *big.Int) *glvScalar {
+	split := ecc.SplitScalar(k, &glvBasis)
+	g := &glvScalar{k: k}
+	if split[0].Sign() < 0 {
+		g.neg1 = true
+		split[0].Neg(&split[0])
+	}
+	if split[1].Sign() < 0 {
+		g.neg2 = true
+		split[1].Neg(&split[1])
+	}
+	bitLen := split[0].BitLen()
+	if split[1].BitLen() > bitLen {
+		bitLen = split[1].BitLen()
+	}
+	g.digits = make([]uint8, (bitLen+1)/2)
+	for j := range g.digits {
+		g.digits[j] = uint8(split[1].Bit(2*j+1)<<3 | split[1].Bit(2*j)<<2 | split[0].Bit(2*j+1)<<1 | split[0].Bit(2*j))
+	}
+	return g
+}
+
+// mulAddG1 sets out[i] = L[i] + ks[i].k·R[i], or ks[i].k·R[i] if L is nil.
+//
+// The scalar multiplications run in lockstep with the 2-bit GLV windows of the
+// scalars, using affine formulas whose denominators are inverted together, so
+// each doubling or addition step costs a single field inversion for all the
+// points. Points that hit an exceptional case of the affine formulas are
+// recomputed in Jacobian coordinates. out can alias L.
+func mulAddG1(out, L, R []bls12381.G1Affine, ks []*glvScalar) {
+	n := len(R)
+	if n < batchAffineMinSize {
+		mulAddG1Jac(out, L, R, ks)
+		return
+	}
+
+	var b batchAffine
+	bad := make([]bool, n)
+	all := make([]int, 0, n)
+	for i := range R {
+		if R[i].IsInfinity() {
+			bad[i] = true
+			continue
+		}
+		all = append(all, i)
+	}
+
+	// tables[d-1][i] = |k2|_j·B + |k1|_j·A for the window d = 4·|k2|_j + |k1|_j,
+	// where A = ±R[i] and B = ±ϕ(R[i]) carry the signs of k1 and k2.
+	var tables [15][]bls12381.G1Affine
+	for d := range tables {
+		tables[d] = make([]bls12381.G1Affine, n)
+	}
+	for _, i := range all {
+		tables[0][i] = R[i]
+		if ks[i].neg1 {
+			tables[0][i].Neg(&tables[0][i])
+		}
+	}
+	copy(tables[1], tables[0])
+	b.double(tables[1], all, bad)
+	copy(tables[2], tables[1])
+	b.add(tables[2], tables[0], all, bad)
+	for _, i := range all {
+		for a := 1; a <= 3; a++ {
+			B := &tables[4*a-1][i]
+			B.X.Mul(&tables[a-1][i].X, &glvOmega)
+			B.Y = tables[a-1][i].Y
+			if ks[i].neg1 != ks[i].neg2 {
+				B.Y.Neg(&B.Y)
+			}
+		}
+	}
+	for a := 1; a <= 3; a++ {
+		for c := 1; c <= 3; c++ {
+			copy(tables[4*a+c-1], tables[4*a-1])
+			b.add(tables[4*a+c-1], tables[c-1], all, bad)
+		}
+	}
+
+	nbDigits := 0
+	for _, i := range all {
+		if len(ks[i].digits) > nbDigits {
+			nbDigits = len(ks[i].digits)
+		}
+	}
+	acc := make([]bls12381.G1Affine, n)
+	started := make([]bool, n)
+	var startedIdx []int
+	addIdx := make([]int, 0, n)
+	addOps := make([]*bls12381.G1Affine, n)
+	for j := nbDigits - 1; j >= 0; j-- {
+		startedIdx = startedIdx[:0]
+		for _, i := range all {
+			if started[i] && !bad[i] {
+				startedIdx = append(startedIdx, i)
+			}
+		}
+		b.double(acc, startedIdx, bad)
+		b.double(acc, startedIdx, bad)
+
+		addIdx = addIdx[:0]
+		for _, i := range all {
+			if bad[i] || j >= len(ks[i].digits) || ks[i].digits[j] == 0 {
+				continue
+			}
+			if !started[i] {
+				acc[i] = tables[ks[i].digits[j]-1][i]
+				started[i] = true
+				continue
+			}
+			addIdx = append(addIdx, i)
+			addOps[i] = &tables[ks[i].digits[j]-1][i]
+		}
+		b.addPtrs(acc, addOps, addIdx, bad)
+	}
+
+	if L != nil {
+		addIdx = addIdx[:0]
+		for i := range L {
+			switch {
+			case bad[i]:
+			case !started[i]:
+				acc[i] = L[i]
+			case !L[i].IsInfinity():
+				addIdx = append(addIdx, i)
+				addOps[i] = &L[i]
+			}
+		}
+		b.addPtrs(acc, addOps, addIdx, bad)
+	}
+
+	for i := range bad {
+		if !bad[i] {
+			continue
+		}
+		var Li []bls12381.G1Affine
+		if L != nil {
+			Li = L[i : i+1]
+		}
+		mulAddG1Jac(acc[i:i+1], Li, R[i:i+1], ks[i:i+1])
+	}
+	copy(out, acc)
+}
+
+// mulAddG1Jac computes the same as mulAddG1 in Jacobian coordinates, converting
+// the results back to affine with a single batch inversion.
+func mulAddG1Jac(out, L, R []bls12381.G1Affine, ks []*glvScalar) {
+	jacs := make([]bls12381.G1Jac, len(R))
+	for i := range R {
+		jacs[i].FromAffine(&R[i])
+		jacs[i].ScalarMultiplication(&jacs[i], ks[i].k)
+		if L != nil {
+			jacs[i].AddMixed(&L[i])
+		}
+	}
+	copy(out, bls12381.BatchJacobianToAffineG1(jacs))
+}
+
+// batchAffine holds the scratch space of the batch inversions.
+type batchAffine struct {
+	dens, prods []fp.Element
+}
+
+// invert replaces b.dens[:n] with their inverses using Montgomery's trick. None
+// of them can be zero.
+func (b *batchAffine) invert(n int) {
+	if n == 0 {
+		return
+	}
+	if cap(b.prods) < n {
+		b.prods = make([]fp.Element, n)
+	}
+	prods := b.prods[:n]
+	prods[0] = b.dens[0]
+	for j := 1; j < n; j++ {
+		prods[j].Mul(&prods[j-1], &b.dens[j])
+	}
+	var inv, tmp fp.Element
+	inv.Inverse(&prods[n-1])
+	for j := n - 1; j > 0; j-- {
+		tmp.Mul(&inv, &prods[j-1])
+		inv.Mul(&inv, &b.dens[j])
+		b.dens[j] = tmp
+	}
+	b.dens[0] = inv
+}
+
+func (b *batchAffine) setDen(j int, den *fp.Element) {
+	if j == len(b.dens) {
+		b.dens = append(b.dens, *den)
+		return
+	}
+	b.dens[j] = *den
+}
+
+// double sets p[i] = 2·p[i] for i in idx, marking as bad the points with y = 0.
+func (b *batchAffine) double(p []bls12381.G1Affine, idx []int, bad []bool) {
+	b.dens = b.dens[:0]
+	var den fp.Element
+	for j, i := range idx {
+		den.Double(&p[i].Y)
+		if den.IsZero() {
+			bad[i] = true
+			den.SetOne()
+		}
+		b.setDen(j, &den)
+	}
+	b.invert(len(idx))
+
+	var lambda, tmp fp.Element
+	for j, i := range idx {
+		if bad[i] {
+			continue
+		}
+		// λ = 3x²/2y, x' = λ² - 2x, y' = λ(x - x') - y
+		tmp.Square(&p[i].X)
+		lambda.Double(&tmp).Add(&lambda, &tmp).Mul(&lambda, &b.dens[j])
+		tmp.Set(&p[i].X)
+		p[i].X.Square(&lambda).Sub(&p[i].X, &tmp).Sub(&p[i].X, &tmp)
+		tmp.Sub(&tmp, &p[i].X).Mul(&tmp, &lambda)
+		p[i].Y.Sub(&tmp, &p[i].Y)
+	}
+}
+
+// add sets p[i] = p[i] + q[i] for i in idx.
+func (b *batchAffine) add(p, q []bls12381.G1Affine, idx []int, bad []bool) {
+	b.addFn(p, func(i int) *bls12381.G1Affine { return &q[i] }, idx, bad)
+}
+
+// addPtrs sets p[i] = p[i] + *q[i] for i in idx.
+func (b *batchAffine) addPtrs(p []bls12381.G1Affine, q []*bls12381.G1Affine, idx []int, bad []bool) {
+	b.addFn(p, func(i int) *bls12381.G1Affine { return q[i] }, idx, bad)
+}
+
+// addFn sets p[i] = p[i] + q(i) for i in idx, marking as bad the points where
+// p[i] and q(i) have the same x.
+func (b *batchAffine) addFn(p []bls12381.G1Affine, q func(int) *bls12381.G1Affine, idx []int, bad []bool) {
+	b.dens = b.dens[:0]
+	var den fp.Element
+	for j, i := range idx {
+		if !bad[i] {
+			den.Sub(&q(i).X, &p[i].X)
+		}
+		if bad[i] || den.IsZero() {
+			bad[i] = true
+			den.SetOne()
+		}
+		b.setDen(j, &den)
+	}
+	b.invert(len(idx))
+
+	var lambda, tmp fp.Element
+	for j, i := range idx {
+		if bad[i] {
+			continue
+		}
+		// λ = (y₂ - y₁)/(x₂ - x₁), x' = λ² - x₁ - x₂, y' = λ(x₁ - x') - y₁
+		qi := q(i)
+		lambda.Sub(&qi.Y, &p[i].Y).Mul(&lambda, &b.dens[j])
+		tmp.Set(&p[i].X)
+		p[i].X.Square(&lambda).Sub(&p[i].X, &tmp).Sub(&p[i].X, &qi.X)
+		tmp.Sub(&tmp, &p[i].X).Mul(&tmp, &lambda)
+		p[i].Y.Sub(&tmp, &p[i].Y)
+	}
+}
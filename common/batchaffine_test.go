@@ -0,0 +1,108 @@
+package common
+
+import (
+	"fmt"
+	"math/big"
+	"testing"
+
+	bls12381 "github.com/consensys/gnark-crypto/ecc/bls12-381"
+	"github.com/consensys/gnark-crypto/ecc/bls12-381/fr"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGLVEndomorphism(t *testing.T) {
+	t.Parallel()
+
+	_, _, g1, _ := bls12381.Generators()
+	var expected, phi bls12381.G1Affine
+	expected.ScalarMultiplication(&g1, &glvLambda)
+	phi.X.Mul(&g1.X, &glvOmega)
+	phi.Y = g1.Y
+	require.True(t, expected.Equal(&phi))
+}
+
+func TestMulAddG1(t *testing.T) {
+	t.Parallel()
+
+	rand, err := NewTestingRand(42)
+	require.NoError(t, err)
+
+	for _, n := range []int{1, batchAffineMinSize - 1, batchAffineMinSize, 100} {
+		n := n
+		t.Run(fmt.Sprintf("n=%d", n), func(t *testing.T) {
+			L, err := rand.GetG1Affines(n)
+			require.NoError(t, err)
+			R, err := rand.GetG1Affines(n)
+			require.NoError(t, err)
+			frs, err := rand.GetFrs(n)
+			require.NoError(t, err)
+			scalars := make([]*big.Int, n)
+			for i := range frs {
+				scalars[i] = FrToBigInt(&frs[i])
+			}
+
+			// Exceptional cases of the affine formulas.
+			if n >= 10 {
+				L[0], R[1] = bls12381.G1Affine{}, bls12381.G1Affine{}
+				scalars[2] = big.NewInt(0)
+				scalars[3] = big.NewInt(1)
+				R[3] = L[3]
+				scalars[4] = big.NewInt(1)
+				L[4].Neg(&R[4])
+				L[5].ScalarMultiplication(&R[5], scalars[5])
+				L[6].ScalarMultiplication(&R[6], scalars[6])
+				L[6].Neg(&L[6])
+				scalars[7].Sub(fr.Modulus(), big.NewInt(1))
+			}
+
+			expected := make([]bls12381.G1Affine, n)
+			expectedScaled := make([]bls12381.G1Affine, n)
+			ks := make([]*glvScalar, n)
+			for i := range expected {
+				expectedScaled[i].ScalarMultiplication(&R[i], scalars[i])
+				expected[i].Add(&L[i], &expectedScaled[i])
+				ks[i] = newGLVScalar(scalars[i])
+			}
+
+			out := make([]bls12381.G1Affine, n)
+			mulAddG1(out, L, R, ks)
+			for i := range expected {
+				require.True(t, expected[i].Equal(&out[i]), "i=%d", i)
+			}
+			mulAddG1(out, nil, R, ks)
+			for i := range expected {
+				require.True(t, expectedScaled[i].Equal(&out[i]), "i=%d", i)
+			}
+		})
+	}
+}
+
+func BenchmarkMulAddG1(b *testing.B) {
+	for _, n := range []int{16, 32, 64, 128} {
+		rand, err := NewTestingRand(42)
+		require.NoError(b, err)
+		L, err := rand.GetG1Affines(n)
+		require.NoError(b, err)
+		R, err := rand.GetG1Affines(n)
+		require.NoError(b, err)
+		s, err := rand.GetFr()
+		require.NoError(b, err)
+		k := newGLVScalar(FrToBigInt(&s))
+		ks := make([]*glvScalar, n)
+		for i := range ks {
+			ks[i] = k
+		}
+		out := make([]bls12381.G1Affine, n)
+
+		b.Run(fmt.Sprintf("n=%d/jacobian", n), func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				mulAddG1Jac(out, L, R, ks)
+			}
+		})
+		b.Run(fmt.Sprintf("n=%d/batch-affine", n), func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				mulAddG1(out, L, R, ks)
+			}
+		})
+	}
+}
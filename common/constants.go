@@ -1,3 +1,4 @@
 package common
 
+// N_BLINDERS is the default number of blinders, i.e. the length of the CRS Hs.
 const N_BLINDERS = 4
@@ -0,0 +1,38 @@
+package common
+
+import (
+	"context"
+	"runtime"
+
+	"github.com/consensys/gnark-crypto/ecc"
+)
+
+// Exec controls how provers and verifiers run: the number of goroutines they can
+// use and a context to abort them. The zero value uses every CPU and is never
+// cancelled.
+type Exec struct {
+	Ctx     context.Context
+	NbTasks int
+}
+
+// Err returns the error of the context, if it's done. It's checked between
+// protocol steps and folding rounds, so a cancelled computation stops at the
+// next check.
+func (e Exec) Err() error {
+	if e.Ctx == nil {
+		return nil
+	}
+	return e.Ctx.Err()
+}
+
+// MultiExpConf returns the configuration for an MSM using the whole CPU budget.
+func (e Exec) MultiExpConf() ecc.MultiExpConfig {
+	return ecc.MultiExpConfig{NbTasks: e.nbTasks()}
+}
+
+func (e Exec) nbTasks() int {
+	if e.NbTasks <= 0 {
+		return runtime.NumCPU()
+	}
+	return e.NbTasks
+}
@@ -0,0 +1,56 @@
+package common
+
+import (
+	bls12381 "github.com/consensys/gnark-crypto/ecc/bls12-381"
+	"github.com/consensys/gnark-crypto/ecc/bls12-381/fr"
+)
+
+const (
+	fixedBaseWindowBits = 4
+	fixedBaseWindows    = (fr.Bytes * 8) / fixedBaseWindowBits
+	fixedBaseWindowSize = 1<<fixedBaseWindowBits - 1
+)
+
+// FixedBaseTable speeds up scalar multiplications of a base known in advance,
+// such as a CRS point. It stores j*16^i*P for every 4-bit window i and digit j,
+// so a multiplication is at most one mixed addition per window and no doublings.
+type FixedBaseTable struct {
+	table [fixedBaseWindows][fixedBaseWindowSize]bls12381.G1Affine
+}
+
+func NewFixedBaseTable(base *bls12381.G1Jac) *FixedBaseTable {
+	points := make([]bls12381.G1Jac, 0, fixedBaseWindows*fixedBaseWindowSize)
+	windowBase := *base
+	for i := 0; i < fixedBaseWindows; i++ {
+		var acc bls12381.G1Jac
+		for j := 0; j < fixedBaseWindowSize; j++ {
+			acc.AddAssign(&windowBase)
+			points = append(points, acc)
+		}
+		// acc is 15*16^i*P, so adding 16^i*P gives 16^(i+1)*P.
+		windowBase.AddAssign(&acc)
+	}
+	affs := bls12381.BatchJacobianToAffineG1(points)
+
+	var fbt FixedBaseTable
+	for i := 0; i < fixedBaseWindows; i++ {
+		copy(fbt.table[i][:], affs[i*fixedBaseWindowSize:(i+1)*fixedBaseWindowSize])
+	}
+	return &fbt
+}
+
+// Mul returns scalar*P where P is the base of the table.
+func (fbt *FixedBaseTable) Mul(scalar *fr.Element) bls12381.G1Jac {
+	var res bls12381.G1Jac
+	// Bytes returns the canonical big-endian encoding, so the last byte holds the
+	// two least significant windows.
+	scalarBytes := scalar.Bytes()
+	for i := 0; i < fixedBaseWindows; i++ {
+		b := scalarBytes[len(scalarBytes)-1-i/2]
+		digit := (b >> (fixedBaseWindowBits * (i % 2))) & fixedBaseWindowSize
+		if digit != 0 {
+			res.AddMixed(&fbt.table[i][digit-1])
+		}
+	}
+	return res
+}
@@ -0,0 +1,33 @@
+package common
+
+import (
+	"testing"
+
+	bls12381 "github.com/consensys/gnark-crypto/ecc/bls12-381"
+	"github.com/consensys/gnark-crypto/ecc/bls12-381/fr"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFixedBaseTable(t *testing.T) {
+	t.Parallel()
+
+	rand, err := NewTestingRand(42)
+	require.NoError(t, err)
+
+	base, err := rand.GetG1Jac()
+	require.NoError(t, err)
+	fbt := NewFixedBaseTable(&base)
+
+	scalars, err := rand.GetFrs(32)
+	require.NoError(t, err)
+	var minusOne fr.Element
+	minusOne.SetOne().Neg(&minusOne)
+	scalars = append(scalars, fr.NewElement(0), fr.One(), fr.NewElement(16), minusOne)
+
+	for i := range scalars {
+		var expected bls12381.G1Jac
+		expected.ScalarMultiplication(&base, FrToBigInt(&scalars[i]))
+		got := fbt.Mul(&scalars[i])
+		require.True(t, expected.Equal(&got))
+	}
+}
@@ -0,0 +1,76 @@
+package common
+
+import (
+	"math/big"
+
+	bls12381 "github.com/consensys/gnark-crypto/ecc/bls12-381"
+	"github.com/consensys/gnark-crypto/ecc/bls12-381/fr"
+)
+
+// G1Fold describes the folding of two halves of a vector of points:
+// Out[i] = L[i] + Scalar·R[i]. Out can alias L.
+type G1Fold struct {
+	Out    []bls12381.G1Affine
+	L      []bls12381.G1Affine
+	R      []bls12381.G1Affine
+	Scalar *big.Int
+}
+
+// FoldG1 computes all the folds together with mulAddG1, so each step of the
+// scalar multiplications shares a single inversion across all the points.
+func FoldG1(exec Exec, folds ...G1Fold) {
+	total := 0
+	for _, f := range folds {
+		total += len(f.L)
+	}
+	L := make([]bls12381.G1Affine, 0, total)
+	R := make([]bls12381.G1Affine, 0, total)
+	ks := make([]*glvScalar, 0, total)
+	for _, f := range folds {
+		L = append(L, f.L...)
+		R = append(R, f.R...)
+		k := newGLVScalar(f.Scalar)
+		for range f.L {
+			ks = append(ks, k)
+		}
+	}
+
+	exec.ParallelFor(total, func(start, end int) {
+		mulAddG1(L[start:end], L[start:end], R[start:end], ks[start:end])
+	})
+
+	offset := 0
+	for _, f := range folds {
+		copy(f.Out, L[offset:offset+len(f.L)])
+		offset += len(f.L)
+	}
+}
+
+// ScaleG1 sets out[i] = scalars[i]·in[i] with mulAddG1. out can alias in.
+func ScaleG1(exec Exec, out, in []bls12381.G1Affine, scalars []fr.Element) {
+	ks := make([]*glvScalar, len(in))
+	exec.ParallelFor(len(in), func(start, end int) {
+		for i := start; i < end; i++ {
+			ks[i] = newGLVScalar(FrToBigInt(&scalars[i]))
+		}
+		mulAddG1(out[start:end], nil, in[start:end], ks[start:end])
+	})
+}
+
+// FoldingScalars returns the 2^len(challenges) scalars s such that s[i] is the
+// product of challenges[len(challenges)-1-j] for every bit j set in i. These are
+// the coefficients of the original bases in the basis folded with challenges,
+// and they're computed with a single multiplication each, doubling the
+// vector once per challenge.
+func FoldingScalars(challenges []fr.Element) []fr.Element {
+	m := len(challenges)
+	s := make([]fr.Element, 1<<m)
+	s[0] = fr.One()
+	for j := 0; j < m; j++ {
+		half := 1 << j
+		for i := 0; i < half; i++ {
+			s[half+i].Mul(&s[i], &challenges[m-1-j])
+		}
+	}
+	return s
+}
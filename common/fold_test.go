@@ -0,0 +1,136 @@
+package common
+
+import (
+	"fmt"
+	"math/bits"
+	"testing"
+
+	bls12381 "github.com/consensys/gnark-crypto/ecc/bls12-381"
+	"github.com/consensys/gnark-crypto/ecc/bls12-381/fr"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFoldG1(t *testing.T) {
+	t.Parallel()
+
+	rand, err := NewTestingRand(42)
+	require.NoError(t, err)
+
+	n := 33
+	L, err := rand.GetG1Affines(n)
+	require.NoError(t, err)
+	R, err := rand.GetG1Affines(n)
+	require.NoError(t, err)
+	// Identity points and doublings must be handled too.
+	L[3], R[5] = bls12381.G1Affine{}, bls12381.G1Affine{}
+	L[7], R[7] = bls12381.G1Affine{}, bls12381.G1Affine{}
+	R[9] = L[9]
+	s1, err := rand.GetFr()
+	require.NoError(t, err)
+
+	expected := make([]bls12381.G1Affine, n)
+	for i := range expected {
+		var tmp bls12381.G1Affine
+		tmp.ScalarMultiplication(&R[i], FrToBigInt(&s1))
+		expected[i].Add(&L[i], &tmp)
+	}
+
+	// Out aliases L in the first fold.
+	L2 := append([]bls12381.G1Affine(nil), L...)
+	out2 := make([]bls12381.G1Affine, n)
+	FoldG1(
+		Exec{},
+		G1Fold{Out: L2, L: L2, R: R, Scalar: FrToBigInt(&s1)},
+		G1Fold{Out: out2, L: L, R: R, Scalar: FrToBigInt(&s1)},
+	)
+	for i := range expected {
+		require.True(t, expected[i].Equal(&L2[i]), "i=%d", i)
+		require.True(t, expected[i].Equal(&out2[i]), "i=%d", i)
+	}
+
+	scalars, err := rand.GetFrs(n)
+	require.NoError(t, err)
+	scaled := make([]bls12381.G1Affine, n)
+	ScaleG1(Exec{}, scaled, R, scalars)
+	for i := range scaled {
+		var expected bls12381.G1Affine
+		expected.ScalarMultiplication(&R[i], FrToBigInt(&scalars[i]))
+		require.True(t, expected.Equal(&scaled[i]), "i=%d", i)
+	}
+}
+
+func TestFoldingScalars(t *testing.T) {
+	t.Parallel()
+
+	rand, err := NewTestingRand(42)
+	require.NoError(t, err)
+	for m := 0; m <= 8; m++ {
+		challenges, err := rand.GetFrs(m)
+		require.NoError(t, err)
+		require.Equal(t, foldingScalarsNaive(challenges), FoldingScalars(challenges), "m=%d", m)
+	}
+}
+
+func BenchmarkFoldingScalars(b *testing.B) {
+	for _, n := range []int{512, 4096} {
+		rand, err := NewTestingRand(42)
+		require.NoError(b, err)
+		challenges, err := rand.GetFrs(bits.Len(uint(n)) - 1)
+		require.NoError(b, err)
+
+		b.Run(fmt.Sprintf("n=%d/naive", n), func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				_ = foldingScalarsNaive(challenges)
+			}
+		})
+		b.Run(fmt.Sprintf("n=%d/doubling", n), func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				_ = FoldingScalars(challenges)
+			}
+		})
+	}
+}
+
+// foldingScalarsNaive is the O(n log n) computation that the verifiers used to
+// do, multiplying the challenges of the bits set in each index.
+func foldingScalarsNaive(challenges []fr.Element) []fr.Element {
+	m := len(challenges)
+	s := make([]fr.Element, 1<<m)
+	for i := range s {
+		s[i] = fr.One()
+		for j := 0; j < m; j++ {
+			if i&(1<<j) != 0 {
+				s[i].Mul(&s[i], &challenges[m-j-1])
+			}
+		}
+	}
+	return s
+}
+
+func BenchmarkFoldG1(b *testing.B) {
+	for _, n := range []int{128, 256} {
+		rand, err := NewTestingRand(42)
+		require.NoError(b, err)
+		L, err := rand.GetG1Affines(n / 2)
+		require.NoError(b, err)
+		R, err := rand.GetG1Affines(n / 2)
+		require.NoError(b, err)
+		s, err := rand.GetFr()
+		require.NoError(b, err)
+		sBigInt := FrToBigInt(&s)
+		out := make([]bls12381.G1Affine, n/2)
+
+		b.Run(fmt.Sprintf("n=%d/affine", n), func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				for j := range out {
+					out[j].Add(&L[j], (&bls12381.G1Affine{}).ScalarMultiplication(&R[j], sBigInt))
+				}
+			}
+		})
+		b.Run(fmt.Sprintf("n=%d/batch", n), func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				FoldG1(Exec{}, G1Fold{Out: out, L: L, R: R, Scalar: sBigInt})
+			}
+		})
+	}
+}
@@ -0,0 +1,76 @@
+package common
+
+import (
+	"sync"
+
+	"github.com/consensys/gnark-crypto/ecc"
+)
+
+// Parallel runs fns concurrently, at most NbTasks at a time, and waits for all
+// of them. Each fn gets an MSM configuration with its share of the CPU budget.
+// Functions that didn't start before the context was done aren't run and fail
+// with its error. If any failed, it returns the error of the first failing one
+// in argument order.
+func (e Exec) Parallel(fns ...func(conf ecc.MultiExpConfig) error) error {
+	nbTasks := e.nbTasks()
+	nbConcurrent := len(fns)
+	if nbConcurrent > nbTasks {
+		nbConcurrent = nbTasks
+	}
+	conf := ecc.MultiExpConfig{NbTasks: 1}
+	if nbConcurrent > 0 && nbTasks/nbConcurrent > 1 {
+		conf.NbTasks = nbTasks / nbConcurrent
+	}
+
+	errs := make([]error, len(fns))
+	sem := make(chan struct{}, nbTasks)
+	var wg sync.WaitGroup
+	wg.Add(len(fns))
+	for i := range fns {
+		sem <- struct{}{}
+		go func(i int) {
+			defer func() { <-sem; wg.Done() }()
+			if err := e.Err(); err != nil {
+				errs[i] = err
+				return
+			}
+			errs[i] = fns[i](conf)
+		}(i)
+	}
+	wg.Wait()
+
+	for _, err := range errs {
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ParallelFor splits [0, n) into contiguous chunks, one per task, and calls fn
+// concurrently on each of them.
+func (e Exec) ParallelFor(n int, fn func(start, end int)) {
+	nbChunks := e.nbTasks()
+	if nbChunks > n {
+		nbChunks = n
+	}
+	if nbChunks <= 1 {
+		fn(0, n)
+		return
+	}
+
+	chunkSize := (n + nbChunks - 1) / nbChunks
+	var wg sync.WaitGroup
+	for start := 0; start < n; start += chunkSize {
+		end := start + chunkSize
+		if end > n {
+			end = n
+		}
+		wg.Add(1)
+		go func(start, end int) {
+			defer wg.Done()
+			fn(start, end)
+		}(start, end)
+	}
+	wg.Wait()
+}
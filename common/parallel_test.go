@@ -0,0 +1,88 @@
+package common
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync/atomic"
+	"testing"
+
+	"github.com/consensys/gnark-crypto/ecc"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParallel(t *testing.T) {
+	t.Parallel()
+
+	t.Run("errors", func(t *testing.T) {
+		var calls atomic.Int32
+		errFirst, errSecond := errors.New("first"), errors.New("second")
+		err := Exec{}.Parallel(
+			func(ecc.MultiExpConfig) error { calls.Add(1); return nil },
+			func(ecc.MultiExpConfig) error { calls.Add(1); return errFirst },
+			func(ecc.MultiExpConfig) error { calls.Add(1); return errSecond },
+		)
+		require.ErrorIs(t, err, errFirst)
+		require.EqualValues(t, 3, calls.Load())
+
+		require.NoError(t, Exec{}.Parallel())
+	})
+
+	t.Run("budget", func(t *testing.T) {
+		for _, nbTasks := range []int{1, 2, 3, 8} {
+			var running, maxRunning atomic.Int32
+			fns := make([]func(ecc.MultiExpConfig) error, 6)
+			nbConcurrent := nbTasks
+			if nbConcurrent > len(fns) {
+				nbConcurrent = len(fns)
+			}
+			for i := range fns {
+				fns[i] = func(conf ecc.MultiExpConfig) error {
+					n := running.Add(1)
+					defer running.Add(-1)
+					for {
+						cur := maxRunning.Load()
+						if n <= cur || maxRunning.CompareAndSwap(cur, n) {
+							break
+						}
+					}
+					if conf.NbTasks < 1 || (conf.NbTasks > 1 && conf.NbTasks*nbConcurrent > nbTasks) {
+						return fmt.Errorf("conf.NbTasks=%d exceeds the budget", conf.NbTasks)
+					}
+					return nil
+				}
+			}
+			require.NoError(t, Exec{NbTasks: nbTasks}.Parallel(fns...))
+			require.LessOrEqual(t, int(maxRunning.Load()), nbTasks)
+		}
+	})
+
+	t.Run("cancellation", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		var calls atomic.Int32
+		err := Exec{Ctx: ctx, NbTasks: 1}.Parallel(
+			func(ecc.MultiExpConfig) error { calls.Add(1); cancel(); return nil },
+			func(ecc.MultiExpConfig) error { calls.Add(1); return nil },
+		)
+		require.ErrorIs(t, err, context.Canceled)
+		require.EqualValues(t, 1, calls.Load())
+	})
+}
+
+func TestParallelFor(t *testing.T) {
+	t.Parallel()
+
+	for _, nbTasks := range []int{0, 1, 3} {
+		for _, n := range []int{0, 1, 2, 7, 64, 1000} {
+			visits := make([]int32, n)
+			Exec{NbTasks: nbTasks}.ParallelFor(n, func(start, end int) {
+				for i := start; i < end; i++ {
+					atomic.AddInt32(&visits[i], 1)
+				}
+			})
+			for i := range visits {
+				require.EqualValues(t, 1, visits[i], "nbTasks=%d, n=%d, i=%d", nbTasks, n, i)
+			}
+		}
+	}
+}
@@ -1,8 +1,10 @@
 package common
 
 import (
+	crand "crypto/rand"
 	"encoding/binary"
 	"fmt"
+	"io"
 	"math/big"
 
 	bls12381 "github.com/consensys/gnark-crypto/ecc/bls12-381"
@@ -10,13 +12,75 @@ import (
 	"golang.org/x/crypto/sha3"
 )
 
-type Rand struct {
-	rand        sha3.ShakeHash
+var labelHedgedRand = []byte("curdleproofs_hedged_rand")
+
+const hedgedEntropySize = 32
+
+// Rand is the source of randomness used by provers and verifiers.
+//
+// Production callers should use NewSecureRand, or NewHedgedRand when they hold
+// a secret witness to mix in, as the whisk tracker proofs do with k.
+// NewTestingRand is deterministic and must only be used in tests.
+type Rand interface {
+	GetFr() (fr.Element, error)
+	GetFrs(n int) ([]fr.Element, error)
+	GetG1Affine() (bls12381.G1Affine, error)
+	GeneratePermutation(n int) ([]uint32, error)
+}
+
+// ShakeRand is a Rand that reads from a SHAKE256 stream.
+type ShakeRand struct {
+	readerRand
+}
+
+// OSRand is a Rand backed by the operating system CSPRNG.
+type OSRand struct {
+	readerRand
+}
+
+var (
+	_ Rand = (*ShakeRand)(nil)
+	_ Rand = (*OSRand)(nil)
+)
+
+type readerRand struct {
+	rand        io.Reader
 	genG1Jac    bls12381.G1Jac
 	genG1Affine bls12381.G1Affine
 }
 
-func NewRand(seed uint64) (*Rand, error) {
+// NewSecureRand returns a Rand backed by the operating system CSPRNG.
+func NewSecureRand() *OSRand {
+	return &OSRand{readerRand: newReaderRand(crand.Reader)}
+}
+
+// NewHedgedRand returns a Rand that mixes fresh operating system entropy with the
+// witness and transcript. If the operating system CSPRNG is weak or compromised,
+// the output is still unpredictable as long as the witness stays secret.
+func NewHedgedRand(witness []byte, transcript []byte) (*ShakeRand, error) {
+	var entropy [hedgedEntropySize]byte
+	if _, err := io.ReadFull(crand.Reader, entropy[:]); err != nil {
+		return nil, fmt.Errorf("reading os entropy: %s", err)
+	}
+
+	rand := sha3.NewShake256()
+	for _, part := range [][]byte{labelHedgedRand, entropy[:], witness, transcript} {
+		var lenBytes [8]byte
+		binary.BigEndian.PutUint64(lenBytes[:], uint64(len(part)))
+		if _, err := rand.Write(lenBytes[:]); err != nil {
+			return nil, fmt.Errorf("writing length: %s", err)
+		}
+		if _, err := rand.Write(part); err != nil {
+			return nil, fmt.Errorf("writing input: %s", err)
+		}
+	}
+	return &ShakeRand{readerRand: newReaderRand(rand)}, nil
+}
+
+// NewTestingRand returns a deterministic Rand derived from seed. A 64-bit seed
+// doesn't provide enough entropy to hide prover secrets, so it must only be used
+// for testing and reproducible test vectors.
+func NewTestingRand(seed uint64) (*ShakeRand, error) {
 	var seedBytes [8]byte
 	binary.BigEndian.PutUint64(seedBytes[:], seed)
 
@@ -24,18 +88,22 @@ func NewRand(seed uint64) (*Rand, error) {
 	if _, err := rand.Write(seedBytes[:]); err != nil {
 		return nil, fmt.Errorf("writing seed: %s", err)
 	}
+	return &ShakeRand{readerRand: newReaderRand(rand)}, nil
+}
+
+func newReaderRand(r io.Reader) readerRand {
 	g1GenJac, _, g1GenAffine, _ := bls12381.Generators()
-	return &Rand{
-		rand:        rand,
+	return readerRand{
+		rand:        r,
 		genG1Jac:    g1GenJac,
 		genG1Affine: g1GenAffine,
-	}, nil
+	}
 }
 
-func (r *Rand) GetFr() (fr.Element, error) {
+func (r *readerRand) GetFr() (fr.Element, error) {
 	for {
 		var byts [fr.Bytes]byte
-		if _, err := r.rand.Read(byts[:]); err != nil {
+		if _, err := io.ReadFull(r.rand, byts[:]); err != nil {
 			return fr.Element{}, fmt.Errorf("get randomness: %s", err)
 
 		}
@@ -46,7 +114,7 @@ func (r *Rand) GetFr() (fr.Element, error) {
 	}
 }
 
-func (r *Rand) GetFrs(n int) ([]fr.Element, error) {
+func (r *readerRand) GetFrs(n int) ([]fr.Element, error) {
 	var err error
 	ret := make([]fr.Element, n)
 	for i := 0; i < n; i++ {
@@ -58,7 +126,7 @@ func (r *Rand) GetFrs(n int) ([]fr.Element, error) {
 	return ret, nil
 }
 
-func (r *Rand) GetG1Jac() (bls12381.G1Jac, error) {
+func (r *readerRand) GetG1Jac() (bls12381.G1Jac, error) {
 	aff, err := r.GetG1Affine()
 	if err != nil {
 		return bls12381.G1Jac{}, fmt.Errorf("get random G1Affine: %s", err)
@@ -69,7 +137,7 @@ func (r *Rand) GetG1Jac() (bls12381.G1Jac, error) {
 	return res, nil
 }
 
-func (r *Rand) GetG1Affine() (bls12381.G1Affine, error) {
+func (r *readerRand) GetG1Affine() (bls12381.G1Affine, error) {
 	scalar, err := r.GetFr()
 	if err != nil {
 		return bls12381.G1Affine{}, fmt.Errorf("get random Fr: %s", err)
@@ -82,7 +150,7 @@ func (r *Rand) GetG1Affine() (bls12381.G1Affine, error) {
 	return res, nil
 }
 
-func (r *Rand) GetG1Affines(n int) ([]bls12381.G1Affine, error) {
+func (r *readerRand) GetG1Affines(n int) ([]bls12381.G1Affine, error) {
 	var err error
 	ret := make([]bls12381.G1Affine, n)
 	for i := range ret {
@@ -94,20 +162,40 @@ func (r *Rand) GetG1Affines(n int) ([]bls12381.G1Affine, error) {
 	return ret, nil
 }
 
-func (r *Rand) GeneratePermutation(n int) ([]uint32, error) {
+// GeneratePermutation returns a uniformly random permutation of [0, n) using a
+// Fisher-Yates shuffle where each swap index is drawn without modulo bias.
+func (r *readerRand) GeneratePermutation(n int) ([]uint32, error) {
 	permutation := make([]uint32, n)
 	for i := range permutation {
 		permutation[i] = uint32(i)
 	}
-	var tmpBytes [16]byte
 	for i := range permutation {
-		if _, err := r.rand.Read(tmpBytes[:]); err != nil {
-			return nil, fmt.Errorf("get randomness: %s", err)
+		j, err := r.getUint64n(uint64(i + 1))
+		if err != nil {
+			return nil, fmt.Errorf("get random index: %s", err)
 		}
-		tmp := binary.BigEndian.Uint16(tmpBytes[:])
-		j := int(tmp) % (i + 1)
 		permutation[i], permutation[j] = permutation[j], permutation[i]
 	}
 
 	return permutation, nil
 }
+
+// getUint64n returns a uniformly random integer in [0, bound). Draws below
+// 2^64 mod bound are rejected, so the accepted range is a multiple of bound and
+// the final reduction isn't biased.
+func (r *readerRand) getUint64n(bound uint64) (uint64, error) {
+	if bound == 0 {
+		return 0, fmt.Errorf("bound must be positive")
+	}
+	threshold := -bound % bound
+	var tmpBytes [8]byte
+	for {
+		if _, err := io.ReadFull(r.rand, tmpBytes[:]); err != nil {
+			return 0, fmt.Errorf("get randomness: %s", err)
+		}
+		v := binary.BigEndian.Uint64(tmpBytes[:])
+		if v >= threshold {
+			return v % bound, nil
+		}
+	}
+}
@@ -9,7 +9,7 @@ import (
 func TestPermutation(t *testing.T) {
 	t.Parallel()
 
-	rand, err := NewRand(42)
+	rand, err := NewTestingRand(42)
 	require.NoError(t, err)
 
 	n := 10
@@ -23,3 +23,124 @@ func TestPermutation(t *testing.T) {
 		perm = newPerm
 	}
 }
+
+func TestPermutationUniformity(t *testing.T) {
+	t.Parallel()
+
+	// The critical values correspond to a p-value of 0.001. The rand is seeded, so
+	// the test is deterministic.
+	t.Run("all permutations", func(t *testing.T) {
+		t.Parallel()
+
+		rand, err := NewTestingRand(42)
+		require.NoError(t, err)
+
+		n := 4
+		numPerms := 24
+		trials := numPerms * 1000
+		counts := map[[4]uint32]int{}
+		for i := 0; i < trials; i++ {
+			perm, err := rand.GeneratePermutation(n)
+			require.NoError(t, err)
+			counts[[4]uint32(perm)]++
+		}
+		require.Len(t, counts, numPerms)
+
+		observed := make([]int, 0, numPerms)
+		for _, c := range counts {
+			observed = append(observed, c)
+		}
+		chiSquare := chiSquareUniform(observed, float64(trials)/float64(numPerms))
+		require.Less(t, chiSquare, 49.728) // df=23
+	})
+
+	t.Run("positions", func(t *testing.T) {
+		t.Parallel()
+
+		rand, err := NewTestingRand(43)
+		require.NoError(t, err)
+
+		n := 6
+		trials := 60000
+		counts := make([]int, n*n)
+		for i := 0; i < trials; i++ {
+			perm, err := rand.GeneratePermutation(n)
+			require.NoError(t, err)
+			for pos, v := range perm {
+				counts[pos*n+int(v)]++
+			}
+		}
+		chiSquare := chiSquareUniform(counts, float64(trials)/float64(n))
+		require.Less(t, chiSquare, 52.620) // df=(n-1)^2=25
+	})
+}
+
+func TestGetUint64n(t *testing.T) {
+	t.Parallel()
+
+	rand, err := NewTestingRand(42)
+	require.NoError(t, err)
+
+	for _, bound := range []uint64{1, 2, 3, 1<<32 + 1, 1<<63 + 1, ^uint64(0)} {
+		for i := 0; i < 100; i++ {
+			v, err := rand.getUint64n(bound)
+			require.NoError(t, err)
+			require.Less(t, v, bound)
+		}
+	}
+	_, err = rand.getUint64n(0)
+	require.Error(t, err)
+}
+
+func chiSquareUniform(observed []int, expected float64) float64 {
+	var chiSquare float64
+	for _, o := range observed {
+		d := float64(o) - expected
+		chiSquare += d * d / expected
+	}
+	return chiSquare
+}
+
+func TestRandSources(t *testing.T) {
+	t.Parallel()
+
+	t.Run("testing rand is deterministic", func(t *testing.T) {
+		rand1, err := NewTestingRand(42)
+		require.NoError(t, err)
+		rand2, err := NewTestingRand(42)
+		require.NoError(t, err)
+
+		frs1, err := rand1.GetFrs(8)
+		require.NoError(t, err)
+		frs2, err := rand2.GetFrs(8)
+		require.NoError(t, err)
+		require.Equal(t, frs1, frs2)
+	})
+
+	t.Run("secure rand", func(t *testing.T) {
+		rand := NewSecureRand()
+		frs1, err := rand.GetFrs(8)
+		require.NoError(t, err)
+		frs2, err := rand.GetFrs(8)
+		require.NoError(t, err)
+		require.NotEqual(t, frs1, frs2)
+	})
+
+	t.Run("hedged rand", func(t *testing.T) {
+		witness := []byte("witness")
+		transcript := []byte("transcript")
+
+		rand1, err := NewHedgedRand(witness, transcript)
+		require.NoError(t, err)
+		rand2, err := NewHedgedRand(witness, transcript)
+		require.NoError(t, err)
+
+		// Same witness and transcript must still produce different outputs
+		// since fresh OS entropy is mixed in.
+		frs1, err := rand1.GetFrs(8)
+		require.NoError(t, err)
+		frs2, err := rand2.GetFrs(8)
+		require.NoError(t, err)
+		require.NotEqual(t, frs1, frs2)
+	})
+}
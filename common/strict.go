@@ -0,0 +1,95 @@
+package common
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+
+	bls12381 "github.com/consensys/gnark-crypto/ecc/bls12-381"
+	"github.com/consensys/gnark-crypto/ecc/bls12-381/fr"
+)
+
+// ErrMalformedProof is wrapped by the errors returned when a proof can't be
+// decoded or doesn't have the shape the verifier expects.
+var ErrMalformedProof = errors.New("malformed proof")
+
+// SliceLenSize is the size of the length prefix that bls12381.Encoder writes
+// before a slice.
+const SliceLenSize = 4
+
+// StrictDecoder decodes the canonical encoding produced by bls12381.Encoder,
+// rejecting anything else: points must be compressed and in the subgroup, field
+// elements must be reduced, and slices must have the length the caller expects.
+// It never allocates based on lengths read from the stream.
+type StrictDecoder struct {
+	r io.Reader
+}
+
+func NewStrictDecoder(r io.Reader) *StrictDecoder {
+	return &StrictDecoder{r: r}
+}
+
+func (d *StrictDecoder) DecodeG1Affine(p *bls12381.G1Affine) error {
+	var buf [bls12381.SizeOfG1AffineCompressed]byte
+	if _, err := io.ReadFull(d.r, buf[:]); err != nil {
+		return err
+	}
+	// The most significant bit flags the compressed form.
+	if buf[0]&0x80 == 0 {
+		return fmt.Errorf("point isn't compressed")
+	}
+	// SetBytes also checks subgroup membership and that X is canonical.
+	if _, err := p.SetBytes(buf[:]); err != nil {
+		return err
+	}
+	return nil
+}
+
+func (d *StrictDecoder) DecodeG1Jac(p *bls12381.G1Jac) error {
+	var tmp bls12381.G1Affine
+	if err := d.DecodeG1Affine(&tmp); err != nil {
+		return err
+	}
+	p.FromAffine(&tmp)
+	return nil
+}
+
+func (d *StrictDecoder) DecodeFr(e *fr.Element) error {
+	var buf [fr.Bytes]byte
+	if _, err := io.ReadFull(d.r, buf[:]); err != nil {
+		return err
+	}
+	return e.SetBytesCanonical(buf[:])
+}
+
+// DecodeG1JacSlice decodes a length-prefixed slice of points whose length must be expectedLen.
+func (d *StrictDecoder) DecodeG1JacSlice(out *[]bls12381.G1Jac, expectedLen int) error {
+	var lenBuf [SliceLenSize]byte
+	if _, err := io.ReadFull(d.r, lenBuf[:]); err != nil {
+		return err
+	}
+	if l := binary.BigEndian.Uint32(lenBuf[:]); uint64(l) != uint64(expectedLen) {
+		return fmt.Errorf("slice has length %d but expected %d", l, expectedLen)
+	}
+	*out = make([]bls12381.G1Jac, expectedLen)
+	for i := range *out {
+		if err := d.DecodeG1Jac(&(*out)[i]); err != nil {
+			return fmt.Errorf("element %d: %s", i, err)
+		}
+	}
+	return nil
+}
+
+// Log2 returns log2(n) if n is a power of two greater than one.
+func Log2(n int) (int, error) {
+	if n < 2 || n&(n-1) != 0 {
+		return 0, fmt.Errorf("%d isn't a power of two greater than one", n)
+	}
+	lg := 0
+	for n > 1 {
+		n >>= 1
+		lg++
+	}
+	return lg, nil
+}
@@ -1,18 +1,16 @@
 package common
 
 import (
+	"encoding/hex"
 	"errors"
 	"fmt"
 	"math/big"
-	"runtime"
+	"strings"
 
-	"github.com/consensys/gnark-crypto/ecc"
 	bls12381 "github.com/consensys/gnark-crypto/ecc/bls12-381"
 	"github.com/consensys/gnark-crypto/ecc/bls12-381/fr"
 )
 
-var MultiExpConf = ecc.MultiExpConfig{NbTasks: runtime.NumCPU()}
-
 func FrToBigInt(r *fr.Element) *big.Int {
 	var ret big.Int
 	r.BigInt(&ret)
@@ -34,6 +32,28 @@ func IPA(a []fr.Element, b []fr.Element) (fr.Element, error) {
 	return res, nil
 }
 
+// NextPowerOfTwo returns the smallest power of two greater than or equal to n.
+func NextPowerOfTwo(n int) int {
+	p := 1
+	for p < n {
+		p <<= 1
+	}
+	return p
+}
+
+// PadPermutation extends perm to size elements with the identity permutation.
+func PadPermutation(perm []uint32, size int) []uint32 {
+	if len(perm) >= size {
+		return perm
+	}
+	padded := make([]uint32, size)
+	copy(padded, perm)
+	for i := len(perm); i < size; i++ {
+		padded[i] = uint32(i)
+	}
+	return padded
+}
+
 func Permute[T any](vs []T, perm []uint32) []T {
 	ret := make([]T, len(vs))
 	for i := range perm {
@@ -49,7 +69,8 @@ func ShufflePermuteCommit(
 	Ss []bls12381.G1Affine,
 	perm []uint32,
 	k fr.Element,
-	rand *Rand,
+	rand Rand,
+	exec Exec,
 ) ([]bls12381.G1Affine, []bls12381.G1Affine, bls12381.G1Jac, []fr.Element, error) {
 	biK := FrToBigInt(&k)
 	Ts := make([]bls12381.G1Affine, len(Rs))
@@ -65,21 +86,24 @@ func ShufflePermuteCommit(
 	Ts = Permute(Ts, perm)
 	Us = Permute(Us, perm)
 
+	// If there're less elements than bases, the permutation is padded with the
+	// identity so M commits to a permutation of all the bases.
+	perm = PadPermutation(perm, len(crsGs))
 	rangeFrs := make([]fr.Element, len(crsGs))
-	for i := range perm {
+	for i := range rangeFrs {
 		rangeFrs[i] = fr.NewElement(uint64(i))
 	}
 
 	permRangeFrs := Permute(rangeFrs, perm)
 	var M, M2 bls12381.G1Jac
-	if _, err := M.MultiExp(crsGs, permRangeFrs, MultiExpConf); err != nil {
+	if _, err := M.MultiExp(crsGs, permRangeFrs, exec.MultiExpConf()); err != nil {
 		return nil, nil, bls12381.G1Jac{}, nil, fmt.Errorf("calculating M_1: %s", err)
 	}
-	rs_m, err := rand.GetFrs(N_BLINDERS)
+	rs_m, err := rand.GetFrs(len(crsHs))
 	if err != nil {
 		return nil, nil, bls12381.G1Jac{}, nil, fmt.Errorf("getting rs_m: %s", err)
 	}
-	if _, err := M2.MultiExp(crsHs, rs_m, MultiExpConf); err != nil {
+	if _, err := M2.MultiExp(crsHs, rs_m, exec.MultiExpConf()); err != nil {
 		return nil, nil, bls12381.G1Jac{}, nil, fmt.Errorf("calculating M_2: %s", err)
 	}
 	M.AddAssign(&M2)
@@ -99,3 +123,95 @@ func DecodeAffineSliceToJac(d *bls12381.Decoder, out *[]bls12381.G1Jac) error {
 
 	return nil
 }
+
+// G1AffineToHex returns the 0x-prefixed hex encoding of the compressed point.
+func G1AffineToHex(p *bls12381.G1Affine) string {
+	b := p.Bytes()
+	return "0x" + hex.EncodeToString(b[:])
+}
+
+// G1AffineFromHex decodes a 0x-prefixed hex encoded compressed point.
+func G1AffineFromHex(s string) (bls12381.G1Affine, error) {
+	b, err := decodeHex(s, bls12381.SizeOfG1AffineCompressed)
+	if err != nil {
+		return bls12381.G1Affine{}, err
+	}
+	var p bls12381.G1Affine
+	if _, err := p.SetBytes(b); err != nil {
+		return bls12381.G1Affine{}, fmt.Errorf("decoding point: %s", err)
+	}
+	return p, nil
+}
+
+// G1JacToHex returns the 0x-prefixed hex encoding of the compressed point.
+func G1JacToHex(p *bls12381.G1Jac) string {
+	var aff bls12381.G1Affine
+	aff.FromJacobian(p)
+	return G1AffineToHex(&aff)
+}
+
+// G1JacFromHex decodes a 0x-prefixed hex encoded compressed point.
+func G1JacFromHex(s string) (bls12381.G1Jac, error) {
+	aff, err := G1AffineFromHex(s)
+	if err != nil {
+		return bls12381.G1Jac{}, err
+	}
+	var p bls12381.G1Jac
+	p.FromAffine(&aff)
+	return p, nil
+}
+
+// G1JacsToHex is like G1JacToHex for a slice of points, batching the conversion to affine.
+func G1JacsToHex(ps []bls12381.G1Jac) []string {
+	affs := bls12381.BatchJacobianToAffineG1(ps)
+	ret := make([]string, len(affs))
+	for i := range affs {
+		ret[i] = G1AffineToHex(&affs[i])
+	}
+	return ret
+}
+
+// G1JacsFromHex is like G1JacFromHex for a slice of points.
+func G1JacsFromHex(ss []string) ([]bls12381.G1Jac, error) {
+	ret := make([]bls12381.G1Jac, len(ss))
+	for i := range ss {
+		var err error
+		if ret[i], err = G1JacFromHex(ss[i]); err != nil {
+			return nil, fmt.Errorf("element %d: %s", i, err)
+		}
+	}
+	return ret, nil
+}
+
+// FrToHex returns the 0x-prefixed hex encoding of the big-endian scalar.
+func FrToHex(e *fr.Element) string {
+	b := e.Bytes()
+	return "0x" + hex.EncodeToString(b[:])
+}
+
+// FrFromHex decodes a 0x-prefixed hex encoded big-endian scalar, which must be reduced.
+func FrFromHex(s string) (fr.Element, error) {
+	b, err := decodeHex(s, fr.Bytes)
+	if err != nil {
+		return fr.Element{}, err
+	}
+	var e fr.Element
+	if err := e.SetBytesCanonical(b); err != nil {
+		return fr.Element{}, fmt.Errorf("decoding scalar: %s", err)
+	}
+	return e, nil
+}
+
+func decodeHex(s string, size int) ([]byte, error) {
+	if !strings.HasPrefix(s, "0x") {
+		return nil, fmt.Errorf("missing 0x prefix")
+	}
+	b, err := hex.DecodeString(s[2:])
+	if err != nil {
+		return nil, fmt.Errorf("decoding hex: %s", err)
+	}
+	if len(b) != size {
+		return nil, fmt.Errorf("expected %d bytes, got %d", size, len(b))
+	}
+	return b, nil
+}
@@ -1,12 +1,32 @@
 package curdleproof
 
 import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/json"
 	"fmt"
+	"io"
 
+	"github.com/consensys/gnark-crypto/ecc"
 	bls12381 "github.com/consensys/gnark-crypto/ecc/bls12-381"
+	"github.com/consensys/gnark-crypto/ecc/bls12-381/fr"
 	"github.com/jsign/curdleproofs/common"
 )
 
+// dstCRS is the RFC 9380 domain separation tag used to hash CRS bases to G1.
+var dstCRS = []byte("CURDLEPROOFS_CRS_BLS12381G1_XMD:SHA-256_SSWU_RO_")
+
+var (
+	labelCRSGs = []byte("Gs")
+	labelCRSHs = []byte("Hs")
+	labelCRSH  = []byte("H")
+	labelCRSGt = []byte("Gt")
+	labelCRSGu = []byte("Gu")
+
+	labelCRSGsPadding = []byte("Gs_padding")
+)
+
 type CRS struct {
 	Gs   []bls12381.G1Affine
 	Hs   []bls12381.G1Affine
@@ -17,35 +37,203 @@ type CRS struct {
 	Hsum bls12381.G1Affine
 }
 
-func GenerateCRS(size int, rand *common.Rand) (CRS, error) {
-	gs, err := rand.GetG1Affines(size)
+// GenerateCRS generates a CRS with common.N_BLINDERS blinders from rand. The
+// discrete logs of the bases are known to anyone knowing the rand seed, so it
+// must only be used for testing. Use GenerateCRSFromSeed for a
+// nothing-up-my-sleeve CRS.
+//
+// The CRS has exactly size Gs bases and supports shuffling up to size elements.
+// The arguments need len(Gs)+len(Hs) to be a power of two, so Prove and Verify
+// derive the missing bases, see CRS.padded. GeneratePaddedCRS generates them up
+// front instead.
+func GenerateCRS(size int, rand common.Rand) (CRS, error) {
+	return GenerateCRSWithBlinders(size, common.N_BLINDERS, rand)
+}
+
+// GenerateCRSWithBlinders is like GenerateCRS with nBlinders blinders.
+func GenerateCRSWithBlinders(size int, nBlinders int, rand common.Rand) (CRS, error) {
+	if err := checkCRSParams(size, nBlinders); err != nil {
+		return CRS{}, err
+	}
+	gs, err := randG1Affines(rand, size)
 	if err != nil {
 		return CRS{}, fmt.Errorf("gen gs: %s", err)
 	}
-	hs, err := rand.GetG1Affines(common.N_BLINDERS)
+	hs, err := randG1Affines(rand, nBlinders)
 	if err != nil {
 		return CRS{}, fmt.Errorf("gen hs: %s", err)
 	}
-	h, err := rand.GetG1Jac()
+	hgtgu, err := randG1Affines(rand, 3)
+	if err != nil {
+		return CRS{}, fmt.Errorf("gen h, gt and gu: %s", err)
+	}
+	var h, gt, gu bls12381.G1Jac
+	h.FromAffine(&hgtgu[0])
+	gt.FromAffine(&hgtgu[1])
+	gu.FromAffine(&hgtgu[2])
+
+	return newCRS(gs, hs, h, gt, gu), nil
+}
+
+// GenerateCRSFromSeed deterministically derives a CRS from domain. Every base is
+// obtained with the RFC 9380 BLS12381G1_XMD:SHA-256_SSWU_RO_ suite, using a
+// message that binds the domain, the base name and its index. Nobody knows the
+// discrete logs between the resulting bases. It uses common.N_BLINDERS blinders
+// and, like GenerateCRS, has exactly size Gs bases.
+func GenerateCRSFromSeed(domain []byte, size int) (CRS, error) {
+	return GenerateCRSFromSeedWithBlinders(domain, size, common.N_BLINDERS)
+}
+
+// GenerateCRSFromSeedWithBlinders is like GenerateCRSFromSeed with nBlinders blinders.
+func GenerateCRSFromSeedWithBlinders(domain []byte, size int, nBlinders int) (CRS, error) {
+	if err := checkCRSParams(size, nBlinders); err != nil {
+		return CRS{}, err
+	}
+	gs, err := hashToG1Affines(domain, labelCRSGs, size)
+	if err != nil {
+		return CRS{}, fmt.Errorf("gen gs: %s", err)
+	}
+	hs, err := hashToG1Affines(domain, labelCRSHs, nBlinders)
+	if err != nil {
+		return CRS{}, fmt.Errorf("gen hs: %s", err)
+	}
+	h, err := hashToG1Jac(domain, labelCRSH)
 	if err != nil {
 		return CRS{}, fmt.Errorf("gen h: %s", err)
 	}
-	gt, err := rand.GetG1Jac()
+	gt, err := hashToG1Jac(domain, labelCRSGt)
 	if err != nil {
 		return CRS{}, fmt.Errorf("gen gt: %s", err)
 	}
-	gu, err := rand.GetG1Jac()
+	gu, err := hashToG1Jac(domain, labelCRSGu)
 	if err != nil {
 		return CRS{}, fmt.Errorf("gen gu: %s", err)
 	}
-	var gsum bls12381.G1Affine
-	for _, g := range gs {
-		gsum.Add(&gsum, &g)
+
+	return newCRS(gs, hs, h, gt, gu), nil
+}
+
+// GeneratePaddedCRS is like GenerateCRS, but rounds size up so that
+// size+common.N_BLINDERS is a power of two. Prove and Verify then use the bases
+// as they are, without deriving the missing ones.
+func GeneratePaddedCRS(size int, rand common.Rand) (CRS, error) {
+	if err := checkCRSParams(size, common.N_BLINDERS); err != nil {
+		return CRS{}, err
+	}
+	return GenerateCRS(paddedSize(size, common.N_BLINDERS), rand)
+}
+
+// GeneratePaddedCRSFromSeed is like GenerateCRSFromSeed, but rounds size up so
+// that size+common.N_BLINDERS is a power of two. Its first size Gs bases are
+// the ones of GenerateCRSFromSeed.
+func GeneratePaddedCRSFromSeed(domain []byte, size int) (CRS, error) {
+	if err := checkCRSParams(size, common.N_BLINDERS); err != nil {
+		return CRS{}, err
+	}
+	return GenerateCRSFromSeed(domain, paddedSize(size, common.N_BLINDERS))
+}
+
+// MinBlinders is the minimum number of blinders of a CRS. The last two blinder
+// bases are reserved for the commitments to the randomness of T and U, and the
+// rest blind A. With fewer than the default, A is (nearly) a deterministic
+// function of the permutation and the public transcript, so the proof would
+// stop hiding the permutation.
+const MinBlinders = common.N_BLINDERS
+
+func checkCRSParams(size int, nBlinders int) error {
+	if size < 1 {
+		return fmt.Errorf("size must be positive, got %d", size)
 	}
-	var hsum bls12381.G1Affine
-	for _, h := range hs {
-		hsum.Add(&hsum, &h)
+	if nBlinders < MinBlinders {
+		return fmt.Errorf("number of blinders must be at least %d, got %d", MinBlinders, nBlinders)
 	}
+	return nil
+}
+
+// paddedSize returns the smallest number of Gs bases that supports shuffling ell
+// elements with nBlinders blinders. The arguments need len(Gs)+nBlinders to be a
+// power of two, so shorter instances are padded with identity points up to len(Gs).
+func paddedSize(ell int, nBlinders int) int {
+	return common.NextPowerOfTwo(ell+nBlinders) - nBlinders
+}
+
+// padded returns crs with Gs extended to paddedSize(len(Gs), len(Hs)) bases, and
+// MPad, the commitment to the identity permutation on the extra bases. The extra
+// bases are hashed to G1 from the digest of crs, so the prover and the verifier
+// derive the same ones and nobody knows their discrete logs. Since M only
+// commits to the permutation on the bases of crs, Prove and Verify add MPad to
+// it, and the padded positions of the instance are only permuted among
+// themselves, see Instance.pad.
+func (crs *CRS) padded(digest *crsDigest) (CRS, bls12381.G1Jac, error) {
+	size := paddedSize(len(crs.Gs), len(crs.Hs))
+	if size == len(crs.Gs) {
+		return *crs, bls12381.G1Jac{}, nil
+	}
+	d, err := digest.get()
+	if err != nil {
+		return CRS{}, bls12381.G1Jac{}, err
+	}
+	extra, err := hashToG1Affines(d[:], labelCRSGsPadding, size-len(crs.Gs))
+	if err != nil {
+		return CRS{}, bls12381.G1Jac{}, fmt.Errorf("gen gs padding: %s", err)
+	}
+	indexes := make([]fr.Element, len(extra))
+	for i := range indexes {
+		indexes[i].SetUint64(uint64(len(crs.Gs) + i))
+	}
+	var MPad bls12381.G1Jac
+	if _, err := MPad.MultiExp(extra, indexes, ecc.MultiExpConfig{}); err != nil {
+		return CRS{}, bls12381.G1Jac{}, fmt.Errorf("computing MPad: %s", err)
+	}
+
+	gs := make([]bls12381.G1Affine, 0, size)
+	gs = append(gs, crs.Gs...)
+	gs = append(gs, extra...)
+	return newCRS(gs, crs.Hs, crs.H, crs.Gt, crs.Gu), MPad, nil
+}
+
+// VerifyCRSFromSeed recomputes the CRS for domain and checks that it matches crs.
+func VerifyCRSFromSeed(crs CRS, domain []byte) (bool, error) {
+	expected, err := GenerateCRSFromSeedWithBlinders(domain, len(crs.Gs), len(crs.Hs))
+	if err != nil {
+		return false, fmt.Errorf("generating expected crs: %s", err)
+	}
+	return crs.Equal(&expected), nil
+}
+
+// Equal returns true if both CRSs have exactly the same bases.
+func (crs *CRS) Equal(other *CRS) bool {
+	if len(crs.Gs) != len(other.Gs) || len(crs.Hs) != len(other.Hs) {
+		return false
+	}
+	for i := range crs.Gs {
+		if !crs.Gs[i].Equal(&other.Gs[i]) {
+			return false
+		}
+	}
+	for i := range crs.Hs {
+		if !crs.Hs[i].Equal(&other.Hs[i]) {
+			return false
+		}
+	}
+	return crs.H.Equal(&other.H) &&
+		crs.Gt.Equal(&other.Gt) &&
+		crs.Gu.Equal(&other.Gu) &&
+		crs.Gsum.Equal(&other.Gsum) &&
+		crs.Hsum.Equal(&other.Hsum)
+}
+
+func newCRS(gs, hs []bls12381.G1Affine, h, gt, gu bls12381.G1Jac) CRS {
+	var gsumJac, hsumJac bls12381.G1Jac
+	for i := range gs {
+		gsumJac.AddMixed(&gs[i])
+	}
+	for i := range hs {
+		hsumJac.AddMixed(&hs[i])
+	}
+	var gsum, hsum bls12381.G1Affine
+	gsum.FromJacobian(&gsumJac)
+	hsum.FromJacobian(&hsumJac)
 
 	return CRS{
 		Gs:   gs,
@@ -55,5 +243,313 @@ func GenerateCRS(size int, rand *common.Rand) (CRS, error) {
 		Gu:   gu,
 		Gsum: gsum,
 		Hsum: hsum,
-	}, nil
+	}
+}
+
+func randG1Affines(rand common.Rand, n int) ([]bls12381.G1Affine, error) {
+	ret := make([]bls12381.G1Affine, n)
+	for i := range ret {
+		var err error
+		ret[i], err = rand.GetG1Affine()
+		if err != nil {
+			return nil, fmt.Errorf("get random G1Affine: %s", err)
+		}
+	}
+	return ret, nil
+}
+
+func hashToG1Affines(domain []byte, label []byte, n int) ([]bls12381.G1Affine, error) {
+	ret := make([]bls12381.G1Affine, n)
+	for i := range ret {
+		var err error
+		ret[i], err = bls12381.HashToG1(crsHashMessage(domain, label, uint64(i)), dstCRS)
+		if err != nil {
+			return nil, fmt.Errorf("hashing %s[%d]: %s", label, i, err)
+		}
+	}
+	return ret, nil
+}
+
+func hashToG1Jac(domain []byte, label []byte) (bls12381.G1Jac, error) {
+	aff, err := bls12381.HashToG1(crsHashMessage(domain, label, 0), dstCRS)
+	if err != nil {
+		return bls12381.G1Jac{}, fmt.Errorf("hashing %s: %s", label, err)
+	}
+	var res bls12381.G1Jac
+	res.FromAffine(&aff)
+
+	return res, nil
+}
+
+// crsHashMessage returns len(domain) || domain || label || index, with lengths
+// and indexes encoded as big-endian uint64s.
+func crsHashMessage(domain []byte, label []byte, index uint64) []byte {
+	msg := make([]byte, 0, 8+len(domain)+len(label)+8)
+	msg = binary.BigEndian.AppendUint64(msg, uint64(len(domain)))
+	msg = append(msg, domain...)
+	msg = append(msg, label...)
+	msg = binary.BigEndian.AppendUint64(msg, index)
+	return msg
+}
+
+func (crs *CRS) FromReader(r io.Reader) error {
+	d := bls12381.NewDecoder(r)
+
+	if err := d.Decode(&crs.Gs); err != nil {
+		return fmt.Errorf("decoding Gs: %s", err)
+	}
+	if err := d.Decode(&crs.Hs); err != nil {
+		return fmt.Errorf("decoding Hs: %s", err)
+	}
+	var tmp bls12381.G1Affine
+	if err := d.Decode(&tmp); err != nil {
+		return fmt.Errorf("decoding H: %s", err)
+	}
+	crs.H.FromAffine(&tmp)
+	if err := d.Decode(&tmp); err != nil {
+		return fmt.Errorf("decoding Gt: %s", err)
+	}
+	crs.Gt.FromAffine(&tmp)
+	if err := d.Decode(&tmp); err != nil {
+		return fmt.Errorf("decoding Gu: %s", err)
+	}
+	crs.Gu.FromAffine(&tmp)
+	if err := d.Decode(&crs.Gsum); err != nil {
+		return fmt.Errorf("decoding Gsum: %s", err)
+	}
+	if err := d.Decode(&crs.Hsum); err != nil {
+		return fmt.Errorf("decoding Hsum: %s", err)
+	}
+
+	if err := crs.Validate(); err != nil {
+		return fmt.Errorf("invalid crs: %w", err)
+	}
+	return nil
+}
+
+func (crs *CRS) Serialize(w io.Writer) error {
+	e := bls12381.NewEncoder(w)
+	if err := e.Encode(crs.Gs); err != nil {
+		return fmt.Errorf("encoding Gs: %s", err)
+	}
+	if err := e.Encode(crs.Hs); err != nil {
+		return fmt.Errorf("encoding Hs: %s", err)
+	}
+	hgtgu := bls12381.BatchJacobianToAffineG1([]bls12381.G1Jac{crs.H, crs.Gt, crs.Gu})
+	if err := e.Encode(&hgtgu[0]); err != nil {
+		return fmt.Errorf("encoding H: %s", err)
+	}
+	if err := e.Encode(&hgtgu[1]); err != nil {
+		return fmt.Errorf("encoding Gt: %s", err)
+	}
+	if err := e.Encode(&hgtgu[2]); err != nil {
+		return fmt.Errorf("encoding Gu: %s", err)
+	}
+	if err := e.Encode(&crs.Gsum); err != nil {
+		return fmt.Errorf("encoding Gsum: %s", err)
+	}
+	if err := e.Encode(&crs.Hsum); err != nil {
+		return fmt.Errorf("encoding Hsum: %s", err)
+	}
+	return nil
+}
+
+// Digest returns the SHA-256 hash of the serialized CRS.
+func (crs *CRS) Digest() ([32]byte, error) {
+	h := sha256.New()
+	if err := crs.Serialize(h); err != nil {
+		return [32]byte{}, fmt.Errorf("serializing crs: %s", err)
+	}
+	var digest [32]byte
+	copy(digest[:], h.Sum(nil))
+	return digest, nil
+}
+
+// crsJSON is the CRS format distributed by the Ethereum Whisk spec, where every
+// point is a 0x-prefixed hex encoded compressed G1 point.
+type crsJSON struct {
+	Gs   []string `json:"vec_G"`
+	Hs   []string `json:"vec_H"`
+	H    string   `json:"H"`
+	Gt   string   `json:"G_t"`
+	Gu   string   `json:"G_u"`
+	Gsum string   `json:"G_sum"`
+	Hsum string   `json:"H_sum"`
+}
+
+// FromJSONReader reads a CRS in the Whisk spec JSON format.
+func (crs *CRS) FromJSONReader(r io.Reader) error {
+	var cj crsJSON
+	if err := json.NewDecoder(r).Decode(&cj); err != nil {
+		return fmt.Errorf("decoding json: %s", err)
+	}
+
+	var err error
+	crs.Gs = make([]bls12381.G1Affine, len(cj.Gs))
+	for i := range cj.Gs {
+		if crs.Gs[i], err = common.G1AffineFromHex(cj.Gs[i]); err != nil {
+			return fmt.Errorf("decoding vec_G[%d]: %s", i, err)
+		}
+	}
+	crs.Hs = make([]bls12381.G1Affine, len(cj.Hs))
+	for i := range cj.Hs {
+		if crs.Hs[i], err = common.G1AffineFromHex(cj.Hs[i]); err != nil {
+			return fmt.Errorf("decoding vec_H[%d]: %s", i, err)
+		}
+	}
+	for _, p := range []struct {
+		name string
+		hex  string
+		out  *bls12381.G1Jac
+	}{{"H", cj.H, &crs.H}, {"G_t", cj.Gt, &crs.Gt}, {"G_u", cj.Gu, &crs.Gu}} {
+		tmp, err := common.G1AffineFromHex(p.hex)
+		if err != nil {
+			return fmt.Errorf("decoding %s: %s", p.name, err)
+		}
+		p.out.FromAffine(&tmp)
+	}
+	if crs.Gsum, err = common.G1AffineFromHex(cj.Gsum); err != nil {
+		return fmt.Errorf("decoding G_sum: %s", err)
+	}
+	if crs.Hsum, err = common.G1AffineFromHex(cj.Hsum); err != nil {
+		return fmt.Errorf("decoding H_sum: %s", err)
+	}
+
+	if err := crs.Validate(); err != nil {
+		return fmt.Errorf("invalid crs: %w", err)
+	}
+	return nil
+}
+
+// SerializeJSON writes the CRS in the Whisk spec JSON format.
+func (crs *CRS) SerializeJSON(w io.Writer) error {
+	if err := json.NewEncoder(w).Encode(crs); err != nil {
+		return fmt.Errorf("encoding json: %s", err)
+	}
+	return nil
+}
+
+// MarshalJSON encodes the CRS in the Whisk spec JSON format.
+func (crs CRS) MarshalJSON() ([]byte, error) {
+	cj := crsJSON{
+		Gs:   make([]string, len(crs.Gs)),
+		Hs:   make([]string, len(crs.Hs)),
+		Gsum: common.G1AffineToHex(&crs.Gsum),
+		Hsum: common.G1AffineToHex(&crs.Hsum),
+	}
+	for i := range crs.Gs {
+		cj.Gs[i] = common.G1AffineToHex(&crs.Gs[i])
+	}
+	for i := range crs.Hs {
+		cj.Hs[i] = common.G1AffineToHex(&crs.Hs[i])
+	}
+	hgtgu := common.G1JacsToHex([]bls12381.G1Jac{crs.H, crs.Gt, crs.Gu})
+	cj.H, cj.Gt, cj.Gu = hgtgu[0], hgtgu[1], hgtgu[2]
+
+	return json.Marshal(cj)
+}
+
+// UnmarshalJSON decodes and validates a CRS in the Whisk spec JSON format.
+func (crs *CRS) UnmarshalJSON(data []byte) error {
+	return crs.FromJSONReader(bytes.NewReader(data))
+}
+
+// NBlinders returns the number of blinders of the CRS, which is the length of Hs.
+func (crs *CRS) NBlinders() int {
+	return len(crs.Hs)
+}
+
+// Validate checks that the CRS is well formed: it has the expected sizes, all
+// bases are distinct non-identity points in the prime-order subgroup, and Gsum
+// and Hsum are the sums of Gs and Hs.
+func (crs *CRS) Validate() error {
+	if err := crs.checkShape(); err != nil {
+		return err
+	}
+
+	hgtgu := bls12381.BatchJacobianToAffineG1([]bls12381.G1Jac{crs.H, crs.Gt, crs.Gu})
+	bases := make([]bls12381.G1Affine, 0, len(crs.Gs)+len(crs.Hs)+len(hgtgu))
+	bases = append(bases, crs.Gs...)
+	bases = append(bases, crs.Hs...)
+	bases = append(bases, hgtgu...)
+
+	seen := make(map[[bls12381.SizeOfG1AffineCompressed]byte]int, len(bases))
+	for i := range bases {
+		if bases[i].IsInfinity() {
+			return fmt.Errorf("%s is the identity point", crs.baseName(i))
+		}
+		if !bases[i].IsOnCurve() || !bases[i].IsInSubGroup() {
+			return fmt.Errorf("%s isn't in the prime-order subgroup", crs.baseName(i))
+		}
+		key := bases[i].Bytes()
+		if j, ok := seen[key]; ok {
+			return fmt.Errorf("%s is equal to %s", crs.baseName(i), crs.baseName(j))
+		}
+		seen[key] = i
+	}
+
+	return crs.checkSums()
+}
+
+// checkShape checks the sizes of the CRS, which is all Prove and Verify need to
+// run. Unlike Validate, it doesn't look at the bases, so it's cheap.
+func (crs *CRS) checkShape() error {
+	if len(crs.Gs) == 0 {
+		return fmt.Errorf("empty Gs")
+	}
+	if len(crs.Hs) < MinBlinders {
+		return fmt.Errorf("Hs has length %d but expected at least %d", len(crs.Hs), MinBlinders)
+	}
+	return nil
+}
+
+// validateInstance checks that the instance has the shape expected by a CRS with
+// size Gs bases and doesn't contain identity points.
+func validateInstance(instance Instance, size int) error {
+	ell := len(instance.Rs)
+	if ell == 0 {
+		return ErrEmptyInstance
+	}
+	if len(instance.Ss) != ell || len(instance.Ts) != ell || len(instance.Us) != ell {
+		return fmt.Errorf("%w: len(Rs)=%d, len(Ss)=%d, len(Ts)=%d, len(Us)=%d",
+			ErrLengthMismatch, ell, len(instance.Ss), len(instance.Ts), len(instance.Us))
+	}
+	if ell > size {
+		return fmt.Errorf("%w: instance has %d elements but crs supports up to %d", ErrCRSSizeMismatch, ell, size)
+	}
+	for _, v := range []struct {
+		name   string
+		points []bls12381.G1Affine
+	}{{"Rs", instance.Rs}, {"Ss", instance.Ss}, {"Ts", instance.Ts}, {"Us", instance.Us}} {
+		for i := range v.points {
+			if v.points[i].IsInfinity() {
+				return fmt.Errorf("%w: %s[%d]", ErrIdentityPoint, v.name, i)
+			}
+		}
+	}
+	return nil
+}
+
+// baseName returns the name of the i-th base in the Gs, Hs, H, Gt, Gu order.
+func (crs *CRS) baseName(i int) string {
+	if i < len(crs.Gs) {
+		return fmt.Sprintf("Gs[%d]", i)
+	}
+	i -= len(crs.Gs)
+	if i < len(crs.Hs) {
+		return fmt.Sprintf("Hs[%d]", i)
+	}
+	return []string{"H", "Gt", "Gu"}[i-len(crs.Hs)]
+}
+
+// checkSums checks that Gsum and Hsum are the sums of Gs and Hs.
+func (crs *CRS) checkSums() error {
+	expected := newCRS(crs.Gs, crs.Hs, crs.H, crs.Gt, crs.Gu)
+	if !expected.Gsum.Equal(&crs.Gsum) {
+		return fmt.Errorf("the sum of Gs doesn't match Gsum")
+	}
+	if !expected.Hsum.Equal(&crs.Hsum) {
+		return fmt.Errorf("the sum of Hs doesn't match Hsum")
+	}
+	return nil
 }
@@ -0,0 +1,268 @@
+package curdleproof
+
+import (
+	"bytes"
+	"encoding/json"
+	"math/big"
+	"os"
+	"testing"
+
+	bls12381 "github.com/consensys/gnark-crypto/ecc/bls12-381"
+	"github.com/consensys/gnark-crypto/ecc/bls12-381/fp"
+	"github.com/consensys/gnark-crypto/ecc/bls12-381/fr"
+
+	"github.com/jsign/curdleproofs/common"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCRSFromSeed(t *testing.T) {
+	t.Parallel()
+
+	n := 64
+	domain := []byte("curdleproofs_test")
+
+	crs, err := GenerateCRSFromSeed(domain, n-common.N_BLINDERS)
+	require.NoError(t, err)
+	require.Len(t, crs.Gs, n-common.N_BLINDERS)
+	require.Len(t, crs.Hs, common.N_BLINDERS)
+	for i := range crs.Gs {
+		require.True(t, crs.Gs[i].IsInSubGroup())
+	}
+
+	t.Run("deterministic", func(t *testing.T) {
+		crs2, err := GenerateCRSFromSeed(domain, n-common.N_BLINDERS)
+		require.NoError(t, err)
+		require.True(t, crs.Equal(&crs2))
+	})
+
+	t.Run("audit", func(t *testing.T) {
+		ok, err := VerifyCRSFromSeed(crs, domain)
+		require.NoError(t, err)
+		require.True(t, ok)
+
+		ok, err = VerifyCRSFromSeed(crs, []byte("another domain"))
+		require.NoError(t, err)
+		require.False(t, ok)
+
+		tampered := crs
+		tampered.Gs = append([]bls12381.G1Affine(nil), crs.Gs...)
+		tampered.Gs[3] = crs.Gs[4]
+		ok, err = VerifyCRSFromSeed(tampered, domain)
+		require.NoError(t, err)
+		require.False(t, ok)
+	})
+
+	t.Run("distinct bases", func(t *testing.T) {
+		for i := range crs.Gs {
+			for j := i + 1; j < len(crs.Gs); j++ {
+				require.False(t, crs.Gs[i].Equal(&crs.Gs[j]))
+			}
+		}
+	})
+}
+
+func TestCRSEncoding(t *testing.T) {
+	t.Parallel()
+
+	crs, err := GenerateCRSFromSeed([]byte("curdleproofs_test"), 60)
+	require.NoError(t, err)
+
+	t.Run("binary", func(t *testing.T) {
+		buf := bytes.NewBuffer(nil)
+		require.NoError(t, crs.Serialize(buf))
+		expected := buf.Bytes()
+
+		var crs2 CRS
+		require.NoError(t, crs2.FromReader(bytes.NewReader(expected)))
+		require.True(t, crs.Equal(&crs2))
+
+		buf2 := bytes.NewBuffer(nil)
+		require.NoError(t, crs2.Serialize(buf2))
+		require.Equal(t, expected, buf2.Bytes())
+	})
+
+	t.Run("json", func(t *testing.T) {
+		buf := bytes.NewBuffer(nil)
+		require.NoError(t, crs.SerializeJSON(buf))
+
+		var cj map[string]interface{}
+		require.NoError(t, json.Unmarshal(buf.Bytes(), &cj))
+		for _, key := range []string{"vec_G", "vec_H", "H", "G_t", "G_u", "G_sum", "H_sum"} {
+			require.Contains(t, cj, key)
+		}
+
+		var crs2 CRS
+		require.NoError(t, crs2.FromJSONReader(buf))
+		require.True(t, crs.Equal(&crs2))
+	})
+
+	t.Run("json.Marshaler", func(t *testing.T) {
+		encoded, err := json.Marshal(crs)
+		require.NoError(t, err)
+
+		var crs2 CRS
+		require.NoError(t, json.Unmarshal(encoded, &crs2))
+		require.True(t, crs.Equal(&crs2))
+
+		encoded2, err := json.Marshal(&crs2)
+		require.NoError(t, err)
+		require.Equal(t, encoded, encoded2)
+	})
+
+	t.Run("inconsistent sums", func(t *testing.T) {
+		tampered := crs
+		tampered.Gsum = crs.Gs[0]
+		buf := bytes.NewBuffer(nil)
+		require.NoError(t, tampered.Serialize(buf))
+		var crs2 CRS
+		require.Error(t, crs2.FromReader(buf))
+
+		tampered = crs
+		tampered.Hsum = crs.Hs[0]
+		buf = bytes.NewBuffer(nil)
+		require.NoError(t, tampered.SerializeJSON(buf))
+		require.Error(t, crs2.FromJSONReader(buf))
+	})
+}
+
+// TestCRSJSONFixture decodes testdata/crs.json, a CRS written by hand in the
+// format of the Whisk spec CRS file: the spec field names and 0x-prefixed
+// compressed points. Its bases are small multiples of the G1 generator so the
+// expected values don't depend on this package's encoder.
+func TestCRSJSONFixture(t *testing.T) {
+	t.Parallel()
+
+	f, err := os.Open("testdata/crs.json")
+	require.NoError(t, err)
+	defer f.Close()
+	var crs CRS
+	require.NoError(t, crs.FromJSONReader(f))
+
+	multiple := func(k int64) bls12381.G1Affine {
+		var p bls12381.G1Affine
+		p.ScalarMultiplicationBase(big.NewInt(k))
+		return p
+	}
+	require.Len(t, crs.Gs, 4)
+	require.Len(t, crs.Hs, 4)
+	for i := range crs.Gs {
+		expected := multiple(int64(i + 1))
+		require.True(t, expected.Equal(&crs.Gs[i]), "vec_G[%d]", i)
+		expected = multiple(int64(i + 5))
+		require.True(t, expected.Equal(&crs.Hs[i]), "vec_H[%d]", i)
+	}
+	for _, v := range []struct {
+		name string
+		k    int64
+		p    bls12381.G1Jac
+	}{{"H", 100, crs.H}, {"G_t", 101, crs.Gt}, {"G_u", 102, crs.Gu}} {
+		expected := multiple(v.k)
+		var p bls12381.G1Affine
+		p.FromJacobian(&v.p)
+		require.True(t, expected.Equal(&p), v.name)
+	}
+	expected := multiple(10)
+	require.True(t, expected.Equal(&crs.Gsum))
+	expected = multiple(26)
+	require.True(t, expected.Equal(&crs.Hsum))
+
+	// The first bases are the generator and its double, whose compressed
+	// encodings are fixed by the BLS12-381 serialization format.
+	require.Equal(t, "0x97f1d3a73197d7942695638c4fa9ac0fc3688c4f9774b905a14e3a3f171bac586c55e83ff97a1aeffb3af00adb22c6bb", common.G1AffineToHex(&crs.Gs[0]))
+	require.Equal(t, "0xa572cbea904d67468808c8eb50a9450c9721db309128012543902d0ac358a62ae28f75bb8f1c7c42c39a8c5529bf0f4e", common.G1AffineToHex(&crs.Gs[1]))
+
+	// Encoding it back yields the same fields and values.
+	fixture, err := os.ReadFile("testdata/crs.json")
+	require.NoError(t, err)
+	encoded, err := json.Marshal(crs)
+	require.NoError(t, err)
+	require.JSONEq(t, string(fixture), string(encoded))
+}
+
+func TestCRSValidate(t *testing.T) {
+	t.Parallel()
+
+	crs, err := GenerateCRSFromSeed([]byte("curdleproofs_test"), 60)
+	require.NoError(t, err)
+	require.NoError(t, crs.Validate())
+
+	// withGs returns a copy of crs with Gs[i] replaced and the sums recomputed.
+	withGs := func(i int, p bls12381.G1Affine) CRS {
+		gs := append([]bls12381.G1Affine(nil), crs.Gs...)
+		gs[i] = p
+		return newCRS(gs, crs.Hs, crs.H, crs.Gt, crs.Gu)
+	}
+
+	t.Run("identity point", func(t *testing.T) {
+		invalid := withGs(3, bls12381.G1Affine{})
+		require.ErrorContains(t, invalid.Validate(), "Gs[3] is the identity point")
+
+		invalid = crs
+		invalid.Gt = bls12381.G1Jac{}
+		require.ErrorContains(t, invalid.Validate(), "Gt is the identity point")
+	})
+
+	t.Run("duplicate bases", func(t *testing.T) {
+		invalid := withGs(5, crs.Gs[1])
+		require.ErrorContains(t, invalid.Validate(), "Gs[5] is equal to Gs[1]")
+
+		invalid = crs
+		invalid.Gu.FromAffine(&crs.Hs[2])
+		require.ErrorContains(t, invalid.Validate(), "Gu is equal to Hs[2]")
+	})
+
+	t.Run("point outside the subgroup", func(t *testing.T) {
+		invalid := withGs(0, nonSubgroupPoint(t))
+		require.ErrorContains(t, invalid.Validate(), "Gs[0] isn't in the prime-order subgroup")
+	})
+
+	t.Run("wrong Hs length", func(t *testing.T) {
+		invalid := newCRS(crs.Gs, crs.Hs[:MinBlinders-1], crs.H, crs.Gt, crs.Gu)
+		require.ErrorContains(t, invalid.Validate(), "Hs has length")
+	})
+
+	t.Run("any Gs length", func(t *testing.T) {
+		shorter := newCRS(crs.Gs[:len(crs.Gs)-1], crs.Hs, crs.H, crs.Gt, crs.Gu)
+		require.NoError(t, shorter.Validate())
+	})
+
+	t.Run("wrong sums", func(t *testing.T) {
+		invalid := crs
+		invalid.Gsum = crs.Hsum
+		require.ErrorContains(t, invalid.Validate(), "Gsum")
+	})
+
+	t.Run("prover and verifier reject invalid crs", func(t *testing.T) {
+		invalid := withGs(5, crs.Gs[1])
+		_, err := NewProver(invalid)
+		require.ErrorContains(t, err, "invalid crs")
+		_, err = NewVerifier(invalid)
+		require.ErrorContains(t, err, "invalid crs")
+	})
+
+	t.Run("prove and verify reject a crs with a wrong shape", func(t *testing.T) {
+		invalid := newCRS(crs.Gs, crs.Hs[:MinBlinders-1], crs.H, crs.Gt, crs.Gu)
+		_, err := Prove(invalid, nil, nil, nil, nil, bls12381.G1Jac{}, nil, fr.Element{}, nil, common.NewSecureRand())
+		require.ErrorContains(t, err, "Hs has length")
+		_, err = Verify(Proof{}, invalid, nil, nil, nil, nil, bls12381.G1Jac{}, common.NewSecureRand())
+		require.ErrorContains(t, err, "Hs has length")
+	})
+}
+
+// nonSubgroupPoint returns a point on the curve which isn't in the prime-order subgroup.
+func nonSubgroupPoint(t *testing.T) bls12381.G1Affine {
+	var p bls12381.G1Affine
+	four := fp.NewElement(4)
+	for x := uint64(1); ; x++ {
+		p.X.SetUint64(x)
+		var y2 fp.Element
+		y2.Square(&p.X).Mul(&y2, &p.X).Add(&y2, &four)
+		if p.Y.Sqrt(&y2) == nil {
+			continue
+		}
+		require.True(t, p.IsOnCurve())
+		if !p.IsInSubGroup() {
+			return p
+		}
+	}
+}
@@ -1,6 +1,8 @@
 package curdleproof
 
 import (
+	"bytes"
+	"encoding/json"
 	"fmt"
 	"io"
 
@@ -8,33 +10,78 @@ import (
 	"github.com/consensys/gnark-crypto/ecc/bls12-381/fr"
 	"github.com/jsign/curdleproofs/common"
 	"github.com/jsign/curdleproofs/groupcommitment"
-	"github.com/jsign/curdleproofs/msmaccumulator"
 	"github.com/jsign/curdleproofs/samemultiscalarargument"
 	"github.com/jsign/curdleproofs/samepermutationargument"
 	"github.com/jsign/curdleproofs/samescalarargument"
-	"github.com/jsign/curdleproofs/transcript"
 )
 
 var (
 	labelTranscript = []byte("curdleproofs")
+	labelHeader     = []byte("curdleproofs_header")
 	labelStep1      = []byte("curdleproofs_step1")
 	labelVecA       = []byte("curdleproofs_vec_a")
 
-	zeroPoint = bls12381.G1Affine{}
-	zeroFr    = fr.Element{}
+	zeroFr = fr.Element{}
 )
 
+// Instance is the public statement of a shuffle proof: Ts and Us are Rs and Ss
+// permuted and multiplied by a secret scalar, and M commits to the permutation.
+type Instance struct {
+	Rs []bls12381.G1Affine
+	Ss []bls12381.G1Affine
+	Ts []bls12381.G1Affine
+	Us []bls12381.G1Affine
+	M  bls12381.G1Jac
+}
+
+// pad returns the instance with Rs, Ss, Ts and Us padded to size elements with
+// identity points. Since the identity is fixed by any scalar and the instance
+// elements are never the identity, the padded positions can only be permuted
+// among themselves.
+func (in Instance) pad(size int) Instance {
+	if len(in.Rs) >= size {
+		return in
+	}
+	padPoints := func(ps []bls12381.G1Affine) []bls12381.G1Affine {
+		padded := make([]bls12381.G1Affine, size)
+		copy(padded, ps)
+		return padded
+	}
+	return Instance{
+		Rs: padPoints(in.Rs),
+		Ss: padPoints(in.Ss),
+		Ts: padPoints(in.Ts),
+		Us: padPoints(in.Us),
+		M:  in.M,
+	}
+}
+
+// multiscalarSuffixes returns the points appended to Ts and Us in the same
+// multiscalar argument, matching the Hs[:nBlinders-2] || Gt || Gu suffix of its
+// bases: zeros for the blinders, followed by H || 0 for Ts and 0 || H for Us.
+func multiscalarSuffixes(nBlinders int, H bls12381.G1Affine) ([]bls12381.G1Affine, []bls12381.G1Affine) {
+	TsSuffix := make([]bls12381.G1Affine, nBlinders)
+	UsSuffix := make([]bls12381.G1Affine, nBlinders)
+	TsSuffix[nBlinders-2] = H
+	UsSuffix[nBlinders-1] = H
+	return TsSuffix, UsSuffix
+}
+
 type Proof struct {
 	A                    bls12381.G1Jac
 	T                    groupcommitment.GroupCommitment
 	U                    groupcommitment.GroupCommitment
 	R                    bls12381.G1Jac
 	S                    bls12381.G1Jac
-	proofSamePermutation samepermutationargument.Proof
-	proofSameScalar      samescalarargument.Proof
-	proofSameMultiscalar samemultiscalarargument.Proof
+	ProofSamePermutation samepermutationargument.Proof
+	ProofSameScalar      samescalarargument.Proof
+	ProofSameMultiscalar samemultiscalarargument.Proof
 }
 
+// Prove proves that Ts and Us are a shuffle of Rs and Ss. crs is assumed to be
+// valid: only its shape is checked, so a CRS from an untrusted source must be
+// loaded with FromReader or FromJSONReader, or checked with CRS.Validate. Use a
+// Prover to prove many shuffles.
 func Prove(
 	crs CRS,
 	Rs []bls12381.G1Affine,
@@ -45,157 +92,18 @@ func Prove(
 	perm []uint32,
 	k fr.Element,
 	rs_m []fr.Element,
-	rand *common.Rand,
+	rand common.Rand,
+	opts ...Option,
 ) (Proof, error) {
-	transcript := transcript.New(labelTranscript)
-
-	// Step 1
-	transcript.AppendPointsAffine(labelStep1, Rs...)
-	transcript.AppendPointsAffine(labelStep1, Ss...)
-	transcript.AppendPointsAffine(labelStep1, Ts...)
-	transcript.AppendPointsAffine(labelStep1, Us...)
-	transcript.AppendPoints(labelStep1, M)
-	as := transcript.GetAndAppendChallenges(labelVecA, len(Rs))
-
-	// Step 2
-	rs_a, err := rand.GetFrs(common.N_BLINDERS - 2)
-	if err != nil {
-		return Proof{}, fmt.Errorf("getting rs_a: %s", err)
-	}
-
-	rs_a_prime := make([]fr.Element, 0, len(rs_a)+1+1)
-	rs_a_prime = append(rs_a_prime, rs_a...)
-	rs_a_prime = append(rs_a_prime, zeroFr, zeroFr)
-
-	perm_as := common.Permute(as, perm)
-
-	var A, A_L, A_R bls12381.G1Jac
-	if _, err := A_L.MultiExp(crs.Gs, perm_as, common.MultiExpConf); err != nil {
-		return Proof{}, fmt.Errorf("computing A_L: %s", err)
-	}
-	if _, err := A_R.MultiExp(crs.Hs, rs_a_prime, common.MultiExpConf); err != nil {
-		return Proof{}, fmt.Errorf("computing A_R: %s", err)
-	}
-	A.Set(&A_L).AddAssign(&A_R)
-
-	proofSamePerm, err := samepermutationargument.Prove(
-		samepermutationargument.CRS{
-			Gs: crs.Gs,
-			Hs: crs.Hs,
-			H:  crs.H,
-		},
-		A,
-		M,
-		as,
-		perm,
-		rs_a_prime,
-		rs_m,
-		transcript,
-		rand,
-	)
-	if err != nil {
-		return Proof{}, fmt.Errorf("proving same permutation: %s", err)
-	}
-
-	// Step 3
-	r_t, err := rand.GetFr()
-	if err != nil {
-		return Proof{}, fmt.Errorf("getting random r_t: %s", err)
-	}
-	r_u, err := rand.GetFr()
-	if err != nil {
-		return Proof{}, fmt.Errorf("getting random r_u: %s", err)
-	}
-	var R bls12381.G1Jac
-	if _, err := R.MultiExp(Rs, as, common.MultiExpConf); err != nil {
-		return Proof{}, fmt.Errorf("computing R: %s", err)
-	}
-	var S bls12381.G1Jac
-	if _, err := S.MultiExp(Ss, as, common.MultiExpConf); err != nil {
-		return Proof{}, fmt.Errorf("computing S: %s", err)
-	}
-
-	var tmp bls12381.G1Jac
-	tmp.ScalarMultiplication(&R, common.FrToBigInt(&k))
-	T := groupcommitment.New(crs.Gt, crs.H, tmp, r_t)
-	tmp.ScalarMultiplication(&S, common.FrToBigInt(&k))
-	U := groupcommitment.New(crs.Gu, crs.H, tmp, r_u)
-
-	// TODO(jsign): enforce assumption in callees about mutation of parameters.
-	proofSameScalar, err := samescalarargument.Prove(
-		samescalarargument.CRS{
-			Gt: crs.Gt,
-			Gu: crs.Gu,
-			H:  crs.H,
-		},
-		R,
-		S,
-		T,
-		U,
-		k,
-		r_t,
-		r_u,
-		transcript,
-		rand,
-	)
+	prover, err := newProver(crs)
 	if err != nil {
-		return Proof{}, fmt.Errorf("proving same scalar: %s", err)
+		return Proof{}, err
 	}
-
-	// Step 4
-	A_prime := A
-	A_prime.AddAssign(&T.T_1)
-	A_prime.AddAssign(&U.T_1)
-
-	G := make([]bls12381.G1Affine, 0, len(crs.Gs)+(common.N_BLINDERS-2)+1+1)
-	G = append(G, crs.Gs...)
-	G = append(G, crs.Hs[:common.N_BLINDERS-2]...)
-	gxaffine := bls12381.BatchJacobianToAffineG1([]bls12381.G1Jac{crs.Gt, crs.Gu})
-	G = append(G, gxaffine...)
-
-	T_prime := make([]bls12381.G1Affine, 0, len(Ts)+2+1+1)
-	T_prime = append(T_prime, Ts...)
-	var crsHAffine bls12381.G1Affine
-	crsHAffine.FromJacobian(&crs.H)
-	T_prime = append(T_prime, zeroPoint, zeroPoint, crsHAffine, zeroPoint)
-
-	U_prime := make([]bls12381.G1Affine, 0, len(Us)+2+1+1)
-	U_prime = append(U_prime, Us...)
-	U_prime = append(U_prime, zeroPoint, zeroPoint, zeroPoint)
-	U_prime = append(U_prime, crsHAffine)
-
-	x := make([]fr.Element, 0, len(perm_as)+len(rs_a)+1+1)
-	x = append(x, perm_as...)
-	x = append(x, rs_a...)
-	x = append(x, r_t, r_u)
-
-	proofSameMultiscalar, err := samemultiscalarargument.Prove(
-		G,
-		A_prime,
-		T.T_2,
-		U.T_2,
-		T_prime,
-		U_prime,
-		x,
-		transcript,
-		rand,
-	)
-	if err != nil {
-		return Proof{}, fmt.Errorf("proving same multiscalar: %s", err)
-	}
-
-	return Proof{
-		A,
-		T,
-		U,
-		R,
-		S,
-		proofSamePerm,
-		proofSameScalar,
-		proofSameMultiscalar,
-	}, nil
+	return prover.Prove(Rs, Ss, Ts, Us, M, perm, k, rs_m, rand, opts...)
 }
 
+// Verify verifies a shuffle proof. As in Prove, only the shape of crs is
+// checked. Use a Verifier to verify many proofs.
 func Verify(
 	proof Proof,
 	crs CRS,
@@ -204,117 +112,49 @@ func Verify(
 	Ts []bls12381.G1Affine,
 	Us []bls12381.G1Affine,
 	M bls12381.G1Jac,
-	rand *common.Rand,
+	rand common.Rand,
+	opts ...Option,
 ) (bool, error) {
-	transcript := transcript.New(labelTranscript)
-	msmAccumulator := msmaccumulator.New()
-
-	// Make sure that randomizer was not the zero element (and wiped out the ciphertexts)
-	if Ts[0].IsInfinity() {
-		return false, fmt.Errorf("randomizer is zero")
-	}
-
-	// Step 1
-	transcript.AppendPointsAffine(labelStep1, Rs...)
-	transcript.AppendPointsAffine(labelStep1, Ss...)
-	transcript.AppendPointsAffine(labelStep1, Ts...)
-	transcript.AppendPointsAffine(labelStep1, Us...)
-	transcript.AppendPoints(labelStep1, M)
-	as := transcript.GetAndAppendChallenges(labelVecA, len(Rs))
-
-	// Step 2
-	ok, err := samepermutationargument.Verify(
-		proof.proofSamePermutation,
-		samepermutationargument.CRS{
-			Gs: crs.Gs,
-			Hs: crs.Hs,
-			H:  crs.H,
-		},
-		crs.Gsum,
-		crs.Hsum,
-		proof.A,
-		M,
-		as,
-		common.N_BLINDERS,
-		transcript,
-		msmAccumulator,
-		rand,
-	)
+	verifier, err := newVerifier(crs)
 	if err != nil {
-		return false, fmt.Errorf("verifying same permutation: %s", err)
+		return false, err
 	}
-	if !ok {
-		return false, nil
-	}
-
-	// Step 3
-	if ok := samescalarargument.Verify(
-		proof.proofSameScalar,
-		samescalarargument.CRS{
-			Gt: crs.Gt,
-			Gu: crs.Gu,
-			H:  crs.H,
-		},
-		proof.R,
-		proof.S,
-		proof.T,
-		proof.U,
-		transcript,
-	); !ok {
-		return false, nil
-	}
-
-	// Step 4
-	Aprime := proof.A
-	Aprime.AddAssign(&proof.T.T_1).AddAssign(&proof.U.T_1)
-
-	Gs := make([]bls12381.G1Affine, 0, len(crs.Gs)+(common.N_BLINDERS-2)+1+1)
-	Gs = append(Gs, crs.Gs...)
-	Gs = append(Gs, crs.Hs[:common.N_BLINDERS-2]...)
-	gaffs := bls12381.BatchJacobianToAffineG1([]bls12381.G1Jac{crs.Gt, crs.Gu})
-	Gs = append(Gs, gaffs...)
-
-	Tsprime := make([]bls12381.G1Affine, 0, len(Ts)+2+1+1)
-	Tsprime = append(Tsprime, Ts...)
-	var HAff bls12381.G1Affine
-	HAff.FromJacobian(&crs.H)
-	Tsprime = append(Tsprime, zeroPoint, zeroPoint, HAff, zeroPoint)
-
-	Usprime := make([]bls12381.G1Affine, 0, len(Us)+2+1+1)
-	Usprime = append(Usprime, Us...)
-	Usprime = append(Usprime, zeroPoint, zeroPoint, zeroPoint, HAff)
+	return verifier.Verify(proof, Rs, Ss, Ts, Us, M, rand, opts...)
+}
 
-	ok, err = samemultiscalarargument.Verify(
-		proof.proofSameMultiscalar,
-		Gs,
-		Aprime,
-		proof.T.T_2,
-		proof.U.T_2,
-		Tsprime,
-		Usprime,
-		transcript,
-		msmAccumulator,
-		rand,
-	)
+// VerifyBatch verifies many proofs at once. See Verifier.VerifyBatch.
+func VerifyBatch(
+	crs CRS,
+	instances []Instance,
+	proofs []Proof,
+	rand common.Rand,
+	opts ...Option,
+) (bool, int, error) {
+	verifier, err := newVerifier(crs)
 	if err != nil {
-		return false, fmt.Errorf("verifying same multiscalar: %s", err)
-	}
-	if !ok {
-		return false, nil
-	}
-
-	if err := msmAccumulator.AccumulateCheck(proof.R, as, Rs, rand); err != nil {
-		return false, fmt.Errorf("msm accumulator check R, as, Rs: %s", err)
-	}
-	if err := msmAccumulator.AccumulateCheck(proof.S, as, Ss, rand); err != nil {
-		return false, fmt.Errorf("msm accumulator check S, as, Ss: %s", err)
+		return false, -1, err
 	}
+	return verifier.VerifyBatch(instances, proofs, rand, opts...)
+}
 
-	ok, err = msmAccumulator.Verify()
+// Diagnose returns the labels of the failing checks of the proof. See
+// Verifier.Diagnose.
+func Diagnose(
+	proof Proof,
+	crs CRS,
+	Rs []bls12381.G1Affine,
+	Ss []bls12381.G1Affine,
+	Ts []bls12381.G1Affine,
+	Us []bls12381.G1Affine,
+	M bls12381.G1Jac,
+	rand common.Rand,
+	opts ...Option,
+) ([]string, error) {
+	verifier, err := newVerifier(crs)
 	if err != nil {
-		return false, fmt.Errorf("verifying msm accumulator: %s", err)
+		return nil, err
 	}
-	return ok, nil
+	return verifier.Diagnose(proof, Rs, Ss, Ts, Us, M, rand, opts...)
 }
 
 func (p *Proof) FromReader(r io.Reader) error {
@@ -322,36 +162,178 @@ func (p *Proof) FromReader(r io.Reader) error {
 	d := bls12381.NewDecoder(r)
 
 	if err := d.Decode(&tmp); err != nil {
-		return fmt.Errorf("decoding A: %s", err)
+		return fmt.Errorf("%w: decoding A: %s", common.ErrMalformedProof, err)
 	}
 	p.A.FromAffine(&tmp)
 
 	if err := p.T.FromReader(r); err != nil {
-		return fmt.Errorf("decoding T: %s", err)
+		return fmt.Errorf("decoding T: %w", err)
 	}
 	if err := p.U.FromReader(r); err != nil {
-		return fmt.Errorf("decoding U: %s", err)
+		return fmt.Errorf("decoding U: %w", err)
 	}
 	if err := d.Decode(&tmp); err != nil {
-		return fmt.Errorf("decoding R: %s", err)
+		return fmt.Errorf("%w: decoding R: %s", common.ErrMalformedProof, err)
 	}
 	p.R.FromAffine(&tmp)
 
 	if err := d.Decode(&tmp); err != nil {
-		return fmt.Errorf("decoding S: %s", err)
+		return fmt.Errorf("%w: decoding S: %s", common.ErrMalformedProof, err)
 	}
 	p.S.FromAffine(&tmp)
 
-	if err := p.proofSamePermutation.FromReader(r); err != nil {
-		return fmt.Errorf("decoding proofSamePermutation: %s", err)
+	if err := p.ProofSamePermutation.FromReader(r); err != nil {
+		return fmt.Errorf("decoding ProofSamePermutation: %w", err)
+	}
+	if err := p.ProofSameScalar.FromReader(r); err != nil {
+		return fmt.Errorf("decoding ProofSameScalar: %w", err)
+	}
+	if err := p.ProofSameMultiscalar.FromReader(r); err != nil {
+		return fmt.Errorf("decoding ProofSameMultiscalar: %w", err)
+	}
+
+	return nil
+}
+
+// MarshalBinary implements encoding.BinaryMarshaler using the Serialize format.
+func (p *Proof) MarshalBinary() ([]byte, error) {
+	buf := bytes.NewBuffer(nil)
+	if err := p.Serialize(buf); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// UnmarshalBinary implements encoding.BinaryUnmarshaler, rejecting trailing data.
+func (p *Proof) UnmarshalBinary(data []byte) error {
+	r := bytes.NewReader(data)
+	if err := p.FromReader(r); err != nil {
+		return err
+	}
+	if r.Len() != 0 {
+		return fmt.Errorf("%w: %d bytes of trailing data", ErrMalformedProof, r.Len())
+	}
+	return nil
+}
+
+type proofJSON struct {
+	A                    string                          `json:"A"`
+	T                    groupcommitment.GroupCommitment `json:"cm_T"`
+	U                    groupcommitment.GroupCommitment `json:"cm_U"`
+	R                    string                          `json:"R"`
+	S                    string                          `json:"S"`
+	ProofSamePermutation samepermutationargument.Proof   `json:"same_permutation_proof"`
+	ProofSameScalar      samescalarargument.Proof        `json:"same_scalar_proof"`
+	ProofSameMultiscalar samemultiscalarargument.Proof   `json:"same_multiscalar_proof"`
+}
+
+// MarshalJSON encodes the proof nested by sub-argument, with 0x-prefixed
+// compressed points and scalars.
+func (p Proof) MarshalJSON() ([]byte, error) {
+	ars := common.G1JacsToHex([]bls12381.G1Jac{p.A, p.R, p.S})
+	return json.Marshal(proofJSON{
+		A:                    ars[0],
+		T:                    p.T,
+		U:                    p.U,
+		R:                    ars[1],
+		S:                    ars[2],
+		ProofSamePermutation: p.ProofSamePermutation,
+		ProofSameScalar:      p.ProofSameScalar,
+		ProofSameMultiscalar: p.ProofSameMultiscalar,
+	})
+}
+
+func (p *Proof) UnmarshalJSON(data []byte) error {
+	var pj proofJSON
+	if err := json.Unmarshal(data, &pj); err != nil {
+		return err
+	}
+	for _, v := range []struct {
+		name string
+		hex  string
+		out  *bls12381.G1Jac
+	}{{"A", pj.A, &p.A}, {"R", pj.R, &p.R}, {"S", pj.S, &p.S}} {
+		var err error
+		if *v.out, err = common.G1JacFromHex(v.hex); err != nil {
+			return fmt.Errorf("%w: decoding %s: %s", common.ErrMalformedProof, v.name, err)
+		}
+	}
+	p.T, p.U = pj.T, pj.U
+	p.ProofSamePermutation = pj.ProofSamePermutation
+	p.ProofSameScalar = pj.ProofSameScalar
+	p.ProofSameMultiscalar = pj.ProofSameMultiscalar
+	return nil
+}
+
+// ProofSize returns the size of the encoding of a proof generated with a CRS for
+// ell elements and nBlinders blinders.
+func ProofSize(ell int, nBlinders int) (int, error) {
+	if err := checkCRSParams(ell, nBlinders); err != nil {
+		return 0, err
+	}
+	n := paddedSize(ell, nBlinders) + nBlinders
+
+	samePermutationSize, err := samepermutationargument.ProofSize(n)
+	if err != nil {
+		return 0, fmt.Errorf("same permutation proof size: %s", err)
+	}
+	sameMultiscalarSize, err := samemultiscalarargument.ProofSize(n)
+	if err != nil {
+		return 0, fmt.Errorf("same multiscalar proof size: %s", err)
+	}
+	// A, T, U, R, S and the three sub-argument proofs.
+	return 3*bls12381.SizeOfG1AffineCompressed + 2*groupcommitment.Size +
+		samePermutationSize + samescalarargument.ProofSize + sameMultiscalarSize, nil
+}
+
+// FromReaderStrict is like FromReader but only accepts the canonical encoding of
+// a proof generated with a CRS for ell elements and nBlinders blinders: points
+// must be compressed and in the subgroup, scalars must be reduced and vectors
+// must have length log2(len(crs.Gs)+len(crs.Hs)).
+func (p *Proof) FromReaderStrict(r io.Reader, ell int, nBlinders int) error {
+	if err := checkCRSParams(ell, nBlinders); err != nil {
+		return err
+	}
+	n := paddedSize(ell, nBlinders) + nBlinders
+	d := common.NewStrictDecoder(r)
+
+	if err := d.DecodeG1Jac(&p.A); err != nil {
+		return fmt.Errorf("%w: decoding A: %s", common.ErrMalformedProof, err)
+	}
+	if err := p.T.FromReaderStrict(r); err != nil {
+		return fmt.Errorf("decoding T: %w", err)
+	}
+	if err := p.U.FromReaderStrict(r); err != nil {
+		return fmt.Errorf("decoding U: %w", err)
+	}
+	if err := d.DecodeG1Jac(&p.R); err != nil {
+		return fmt.Errorf("%w: decoding R: %s", common.ErrMalformedProof, err)
+	}
+	if err := d.DecodeG1Jac(&p.S); err != nil {
+		return fmt.Errorf("%w: decoding S: %s", common.ErrMalformedProof, err)
 	}
-	if err := p.proofSameScalar.FromReader(r); err != nil {
-		return fmt.Errorf("decoding proofSameScalar: %s", err)
+	if err := p.ProofSamePermutation.FromReaderStrict(r, n); err != nil {
+		return fmt.Errorf("decoding ProofSamePermutation: %w", err)
 	}
-	if err := p.proofSameMultiscalar.FromReader(r); err != nil {
-		return fmt.Errorf("decoding proofSameMultiscalar: %s", err)
+	if err := p.ProofSameScalar.FromReaderStrict(r); err != nil {
+		return fmt.Errorf("decoding ProofSameScalar: %w", err)
 	}
+	if err := p.ProofSameMultiscalar.FromReaderStrict(r, n); err != nil {
+		return fmt.Errorf("decoding ProofSameMultiscalar: %w", err)
+	}
+
+	return nil
+}
 
+// FromBytesStrict decodes a proof with FromReaderStrict and rejects trailing data.
+func (p *Proof) FromBytesStrict(b []byte, ell int, nBlinders int) error {
+	r := bytes.NewReader(b)
+	if err := p.FromReaderStrict(r, ell, nBlinders); err != nil {
+		return err
+	}
+	if r.Len() != 0 {
+		return fmt.Errorf("%w: %d bytes of trailing data", ErrMalformedProof, r.Len())
+	}
 	return nil
 }
 
@@ -359,7 +341,7 @@ func (p *Proof) Serialize(w io.Writer) error {
 	e := bls12381.NewEncoder(w)
 	ars := bls12381.BatchJacobianToAffineG1([]bls12381.G1Jac{p.A, p.R, p.S})
 	if err := e.Encode(&ars[0]); err != nil {
-		panic(fmt.Errorf("encoding A: %s", err))
+		return fmt.Errorf("encoding A: %s", err)
 	}
 	if err := p.T.Serialize(w); err != nil {
 		return fmt.Errorf("encoding T: %s", err)
@@ -373,14 +355,14 @@ func (p *Proof) Serialize(w io.Writer) error {
 	if err := e.Encode(&ars[2]); err != nil {
 		return fmt.Errorf("encoding S: %s", err)
 	}
-	if err := p.proofSamePermutation.Serialize(w); err != nil {
-		return fmt.Errorf("encoding proofSamePermutation: %s", err)
+	if err := p.ProofSamePermutation.Serialize(w); err != nil {
+		return fmt.Errorf("encoding ProofSamePermutation: %s", err)
 	}
-	if err := p.proofSameScalar.Serialize(w); err != nil {
-		return fmt.Errorf("encoding proofSameScalar: %s", err)
+	if err := p.ProofSameScalar.Serialize(w); err != nil {
+		return fmt.Errorf("encoding ProofSameScalar: %s", err)
 	}
-	if err := p.proofSameMultiscalar.Serialize(w); err != nil {
-		return fmt.Errorf("encoding proofSameMultiscalar: %s", err)
+	if err := p.ProofSameMultiscalar.Serialize(w); err != nil {
+		return fmt.Errorf("encoding ProofSameMultiscalar: %s", err)
 	}
 
 	return nil
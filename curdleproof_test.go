@@ -2,7 +2,10 @@ package curdleproof
 
 import (
 	"bytes"
+	"context"
+	"encoding/json"
 	"fmt"
+	"strings"
 	"testing"
 
 	mrand "math/rand"
@@ -19,7 +22,7 @@ func TestCompleteness(t *testing.T) {
 	n := 64
 
 	// Prove.
-	rand, err := common.NewRand(42)
+	rand, err := common.NewTestingRand(42)
 	require.NoError(t, err)
 
 	crs, Rs, Ss, Ts, Us, M, perm, k, rs_m := setup(t, n)
@@ -38,19 +41,234 @@ func TestCompleteness(t *testing.T) {
 	require.NoError(t, err)
 
 	// Verify.
-	rand, err = common.NewRand(43)
+	rand, err = common.NewTestingRand(43)
 	require.NoError(t, err)
 	ok, err := Verify(proof, crs, Rs, Ss, Ts, Us, M, rand)
 	require.NoError(t, err)
 	require.True(t, ok)
 }
 
+func TestDomainSeparation(t *testing.T) {
+	t.Parallel()
+
+	n := 64
+	crs, Rs, Ss, Ts, Us, M, perm, k, rs_m := setup(t, n)
+	context := []byte("whisk_shuffle_epoch_42")
+
+	rand, err := common.NewTestingRand(42)
+	require.NoError(t, err)
+	proof, err := Prove(crs, Rs, Ss, Ts, Us, M, perm, k, rs_m, rand, WithDomainSeparation(context))
+	require.NoError(t, err)
+
+	t.Run("same context", func(t *testing.T) {
+		ok, err := Verify(proof, crs, Rs, Ss, Ts, Us, M, rand, WithDomainSeparation(context))
+		require.NoError(t, err)
+		require.True(t, ok)
+	})
+
+	t.Run("different context", func(t *testing.T) {
+		ok, err := Verify(proof, crs, Rs, Ss, Ts, Us, M, rand, WithDomainSeparation([]byte("another context")))
+		require.NoError(t, err)
+		require.False(t, ok)
+	})
+
+	t.Run("without header", func(t *testing.T) {
+		ok, err := Verify(proof, crs, Rs, Ss, Ts, Us, M, rand)
+		require.NoError(t, err)
+		require.False(t, ok)
+	})
+
+	t.Run("different crs", func(t *testing.T) {
+		// Same bases, but Gt and Gu swapped, so the CRS digest changes.
+		anotherCRS := crs
+		anotherCRS.Gt, anotherCRS.Gu = crs.Gu, crs.Gt
+		ok, err := Verify(proof, anotherCRS, Rs, Ss, Ts, Us, M, rand, WithDomainSeparation(context))
+		require.NoError(t, err)
+		require.False(t, ok)
+	})
+
+	t.Run("digest is only computed with the header", func(t *testing.T) {
+		verifier, err := NewVerifier(crs)
+		require.NoError(t, err)
+		_, err = verifier.Verify(proof, Rs, Ss, Ts, Us, M, rand)
+		require.NoError(t, err)
+		require.Zero(t, verifier.digest.digest)
+
+		ok, err := verifier.Verify(proof, Rs, Ss, Ts, Us, M, rand, WithDomainSeparation(context))
+		require.NoError(t, err)
+		require.True(t, ok)
+		expected, err := crs.Digest()
+		require.NoError(t, err)
+		require.Equal(t, expected, verifier.digest.digest)
+	})
+}
+
+func TestCustomRand(t *testing.T) {
+	t.Parallel()
+
+	n := 64
+	crs, Rs, Ss, Ts, Us, M, perm, k, rs_m := setup(t, n)
+
+	proveRand := &recordingRand{Rand: common.NewSecureRand()}
+	proof, err := Prove(crs, Rs, Ss, Ts, Us, M, perm, k, rs_m, proveRand)
+	require.NoError(t, err)
+	require.NotZero(t, proveRand.frs)
+
+	verifyRand := &recordingRand{Rand: common.NewSecureRand()}
+	ok, err := Verify(proof, crs, Rs, Ss, Ts, Us, M, verifyRand)
+	require.NoError(t, err)
+	require.True(t, ok)
+	require.NotZero(t, verifyRand.frs)
+}
+
+func TestExecOptions(t *testing.T) {
+	t.Parallel()
+
+	n := 64
+	crs, Rs, Ss, Ts, Us, M, perm, k, rs_m := setup(t, n)
+
+	t.Run("nb tasks", func(t *testing.T) {
+		var encoded [][]byte
+		for _, nbTasks := range []int{1, 3, 0} {
+			rand, err := common.NewTestingRand(42)
+			require.NoError(t, err)
+			proof, err := Prove(crs, Rs, Ss, Ts, Us, M, perm, k, rs_m, rand, WithNbTasks(nbTasks))
+			require.NoError(t, err)
+			ok, err := Verify(proof, crs, Rs, Ss, Ts, Us, M, rand, WithNbTasks(nbTasks))
+			require.NoError(t, err)
+			require.True(t, ok)
+
+			buf := bytes.NewBuffer(nil)
+			require.NoError(t, proof.Serialize(buf))
+			encoded = append(encoded, buf.Bytes())
+		}
+		require.Equal(t, encoded[0], encoded[1])
+		require.Equal(t, encoded[0], encoded[2])
+	})
+
+	t.Run("cancelled context", func(t *testing.T) {
+		rand, err := common.NewTestingRand(42)
+		require.NoError(t, err)
+		proof, err := Prove(crs, Rs, Ss, Ts, Us, M, perm, k, rs_m, rand)
+		require.NoError(t, err)
+
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+		_, err = Prove(crs, Rs, Ss, Ts, Us, M, perm, k, rs_m, rand, WithContext(ctx))
+		require.ErrorIs(t, err, context.Canceled)
+		_, err = Verify(proof, crs, Rs, Ss, Ts, Us, M, rand, WithContext(ctx))
+		require.ErrorIs(t, err, context.Canceled)
+	})
+
+	t.Run("cancelled while proving", func(t *testing.T) {
+		// The context is cancelled when the grand product argument draws its
+		// blinders, so it's noticed by a sub-argument.
+		ctx, cancel := context.WithCancel(context.Background())
+		rand := &cancellingRand{Rand: common.NewSecureRand(), cancel: cancel, after: 2}
+		_, err := Prove(crs, Rs, Ss, Ts, Us, M, perm, k, rs_m, rand, WithContext(ctx))
+		require.ErrorIs(t, err, context.Canceled)
+		require.ErrorContains(t, err, "proving same permutation")
+	})
+}
+
+func TestDiagnose(t *testing.T) {
+	t.Parallel()
+
+	n := 64
+	crs, Rs, Ss, Ts, Us, M, perm, k, rs_m := setup(t, n)
+	rand, err := common.NewTestingRand(42)
+	require.NoError(t, err)
+	proof, err := Prove(crs, Rs, Ss, Ts, Us, M, perm, k, rs_m, rand)
+	require.NoError(t, err)
+
+	failing, err := Diagnose(proof, crs, Rs, Ss, Ts, Us, M, rand)
+	require.NoError(t, err)
+	require.Empty(t, failing)
+
+	one := fr.One()
+	var R0 bls12381.G1Jac
+	R0.FromAffine(&Rs[0])
+	for _, tc := range []struct {
+		name     string
+		tamper   func(p *Proof)
+		expected []string
+	}{
+		{
+			name:     "same scalar Z_k",
+			tamper:   func(p *Proof) { p.ProofSameScalar.Z_k.Add(&p.ProofSameScalar.Z_k, &one) },
+			expected: []string{"same scalar"},
+		},
+		{
+			name:     "ipa C0",
+			tamper:   func(p *Proof) { ipa := &p.ProofSamePermutation.GPAProof.IPAProof; ipa.C0.Add(&ipa.C0, &one) },
+			expected: []string{"ipa check 1"},
+		},
+		{
+			name:     "ipa D0",
+			tamper:   func(p *Proof) { ipa := &p.ProofSamePermutation.GPAProof.IPAProof; ipa.D0.Add(&ipa.D0, &one) },
+			expected: []string{"ipa check 1", "ipa check 2"},
+		},
+		{
+			name:     "same multiscalar X",
+			tamper:   func(p *Proof) { p.ProofSameMultiscalar.X.Add(&p.ProofSameMultiscalar.X, &one) },
+			expected: []string{"same multiscalar A", "same multiscalar T", "same multiscalar U"},
+		},
+		{
+			// S is absorbed by the transcript, so the later challenges change too.
+			name:     "S",
+			tamper:   func(p *Proof) { p.S.AddAssign(&R0) },
+			expected: []string{"same scalar", "same multiscalar A", "same multiscalar T", "same multiscalar U", "S"},
+		},
+	} {
+		tamperedProof := proof
+		tc.tamper(&tamperedProof)
+		failing, err := Diagnose(tamperedProof, crs, Rs, Ss, Ts, Us, M, rand)
+		require.NoError(t, err, tc.name)
+		require.Equal(t, tc.expected, failing, tc.name)
+
+		ok, err := Verify(tamperedProof, crs, Rs, Ss, Ts, Us, M, rand)
+		require.NoError(t, err, tc.name)
+		require.False(t, ok, tc.name)
+	}
+}
+
+// cancellingRand wraps a common.Rand and calls cancel when drawing the after-th
+// vector.
+type cancellingRand struct {
+	common.Rand
+	cancel context.CancelFunc
+	after  int
+}
+
+func (r *cancellingRand) GetFrs(n int) ([]fr.Element, error) {
+	if r.after--; r.after == 0 {
+		r.cancel()
+	}
+	return r.Rand.GetFrs(n)
+}
+
+// recordingRand wraps a common.Rand and counts the drawn field elements.
+type recordingRand struct {
+	common.Rand
+	frs int
+}
+
+func (r *recordingRand) GetFr() (fr.Element, error) {
+	r.frs++
+	return r.Rand.GetFr()
+}
+
+func (r *recordingRand) GetFrs(n int) ([]fr.Element, error) {
+	r.frs += n
+	return r.Rand.GetFrs(n)
+}
+
 func TestSoundness(t *testing.T) {
 	t.Parallel()
 
 	n := 128
 
-	rand, err := common.NewRand(0)
+	rand, err := common.NewTestingRand(0)
 	require.NoError(t, err)
 
 	crs, err := GenerateCRS(n-common.N_BLINDERS, rand)
@@ -71,11 +289,11 @@ func TestSoundness(t *testing.T) {
 	Ss, err := rand.GetG1Affines(n - common.N_BLINDERS)
 	require.NoError(t, err)
 
-	Ts, Us, M, rs_m, err := common.ShufflePermuteCommit(crs.Gs, crs.Hs, Rs, Ss, perm, k, rand)
+	Ts, Us, M, rs_m, err := common.ShufflePermuteCommit(crs.Gs, crs.Hs, Rs, Ss, perm, k, rand, common.Exec{})
 	require.NoError(t, err)
 
 	// Prove.
-	rand, err = common.NewRand(0)
+	rand, err = common.NewTestingRand(0)
 	require.NoError(t, err)
 	proof, err := Prove(
 		crs,
@@ -166,6 +384,29 @@ func TestSoundness(t *testing.T) {
 
 	})
 
+	t.Run("tampered sub-argument scalars", func(t *testing.T) {
+		one := fr.One()
+		tampers := map[string]func(p *Proof){
+			"same scalar Z_k":    func(p *Proof) { p.ProofSameScalar.Z_k.Add(&p.ProofSameScalar.Z_k, &one) },
+			"same multiscalar X": func(p *Proof) { p.ProofSameMultiscalar.X.Add(&p.ProofSameMultiscalar.X, &one) },
+			"ipa C0": func(p *Proof) {
+				ipa := &p.ProofSamePermutation.GPAProof.IPAProof
+				ipa.C0.Add(&ipa.C0, &one)
+			},
+			"ipa D0": func(p *Proof) {
+				ipa := &p.ProofSamePermutation.GPAProof.IPAProof
+				ipa.D0.Add(&ipa.D0, &one)
+			},
+		}
+		for name, tamper := range tampers {
+			tamperedProof := proof
+			tamper(&tamperedProof)
+			ok, err := Verify(tamperedProof, crs, Rs, Ss, Ts, Us, M, rand)
+			require.NoError(t, err, name)
+			require.False(t, ok, name)
+		}
+	})
+
 	t.Run("encode/decode", func(t *testing.T) {
 		buf := bytes.NewBuffer(nil)
 		require.NoError(t, proof.Serialize(buf))
@@ -178,11 +419,18 @@ func TestSoundness(t *testing.T) {
 		require.NoError(t, proof2.Serialize(buf2))
 
 		require.Equal(t, expected, buf2.Bytes())
+
+		marshaled, err := proof.MarshalBinary()
+		require.NoError(t, err)
+		require.Equal(t, expected, marshaled)
+		var proof3 Proof
+		require.NoError(t, proof3.UnmarshalBinary(marshaled))
+		require.Error(t, proof3.UnmarshalBinary(append(marshaled, 0)))
 	})
 }
 
 func BenchmarkProver(b *testing.B) {
-	rand, err := common.NewRand(42)
+	rand, err := common.NewTestingRand(42)
 	require.NoError(b, err)
 
 	for _, n := range []int{64, 128, 256, 512} {
@@ -208,7 +456,7 @@ func BenchmarkProver(b *testing.B) {
 }
 
 func BenchmarkVerifier(b *testing.B) {
-	rand, err := common.NewRand(42)
+	rand, err := common.NewTestingRand(42)
 	require.NoError(b, err)
 
 	for _, n := range []int{64, 128, 256, 512} {
@@ -246,7 +494,7 @@ func setup(t testing.TB, n int) (
 	[]uint32,
 	fr.Element,
 	[]fr.Element) {
-	rand, err := common.NewRand(0)
+	rand, err := common.NewTestingRand(0)
 	require.NoError(t, err)
 
 	crs, err := GenerateCRS(n-common.N_BLINDERS, rand)
@@ -267,8 +515,275 @@ func setup(t testing.TB, n int) (
 	Ss, err := rand.GetG1Affines(n - common.N_BLINDERS)
 	require.NoError(t, err)
 
-	Ts, Us, M, rs_m, err := common.ShufflePermuteCommit(crs.Gs, crs.Hs, Rs, Ss, perm, k, rand)
+	Ts, Us, M, rs_m, err := common.ShufflePermuteCommit(crs.Gs, crs.Hs, Rs, Ss, perm, k, rand, common.Exec{})
 	require.NoError(t, err)
 
 	return crs, Rs, Ss, Ts, Us, M, perm, k, rs_m
 }
+
+func TestStrictDecoding(t *testing.T) {
+	t.Parallel()
+
+	n := 64
+	crs, Rs, Ss, Ts, Us, M, perm, k, rs_m := setup(t, n)
+	proof, err := Prove(crs, Rs, Ss, Ts, Us, M, perm, k, rs_m, common.NewSecureRand())
+	require.NoError(t, err)
+	ell := len(Rs)
+
+	buf := bytes.NewBuffer(nil)
+	require.NoError(t, proof.Serialize(buf))
+	encoded := buf.Bytes()
+
+	t.Run("canonical", func(t *testing.T) {
+		var decoded Proof
+		require.NoError(t, decoded.FromBytesStrict(encoded, ell, common.N_BLINDERS))
+		buf := bytes.NewBuffer(nil)
+		require.NoError(t, decoded.Serialize(buf))
+		require.Equal(t, encoded, buf.Bytes())
+	})
+
+	t.Run("failing writer", func(t *testing.T) {
+		require.ErrorContains(t, proof.Serialize(failingWriter{}), "encoding A")
+	})
+
+	t.Run("uncompressed point", func(t *testing.T) {
+		var A bls12381.G1Affine
+		A.FromJacobian(&proof.A)
+		uncompressed := A.RawBytes()
+		malleated := append(uncompressed[:], encoded[bls12381.SizeOfG1AffineCompressed:]...)
+
+		// The permissive decoder accepts it, the strict one doesn't.
+		var decoded Proof
+		require.NoError(t, decoded.FromReader(bytes.NewReader(malleated)))
+		require.ErrorIs(t, decoded.FromBytesStrict(malleated, ell, common.N_BLINDERS), ErrMalformedProof)
+	})
+
+	t.Run("non-canonical scalar", func(t *testing.T) {
+		malleated := append([]byte(nil), encoded...)
+		for i := len(malleated) - fr.Bytes; i < len(malleated); i++ {
+			malleated[i] = 0xff
+		}
+		var decoded Proof
+		require.ErrorIs(t, decoded.FromBytesStrict(malleated, ell, common.N_BLINDERS), ErrMalformedProof)
+	})
+
+	t.Run("trailing data", func(t *testing.T) {
+		var decoded Proof
+		require.ErrorIs(t, decoded.FromBytesStrict(append(encoded[:len(encoded):len(encoded)], 0), ell, common.N_BLINDERS), ErrMalformedProof)
+	})
+
+	t.Run("wrong size", func(t *testing.T) {
+		var decoded Proof
+		require.ErrorIs(t, decoded.FromBytesStrict(encoded, 2*n-common.N_BLINDERS, common.N_BLINDERS), ErrMalformedProof)
+		// A smaller instance uses the same CRS size, hence the same proof size.
+		require.NoError(t, decoded.FromBytesStrict(encoded, ell-1, common.N_BLINDERS))
+	})
+
+	t.Run("huge declared slice length", func(t *testing.T) {
+		// The first vector length prefix is in the inner product argument of the
+		// same permutation proof: A, T, U, R, S, B, C, Rp, B_c and B_d come before.
+		offset := 9*bls12381.SizeOfG1AffineCompressed + 2*bls12381.SizeOfG1AffineCompressed + fr.Bytes
+		malleated := append([]byte(nil), encoded...)
+		copy(malleated[offset:], []byte{0xff, 0xff, 0xff, 0xff})
+		var decoded Proof
+		err := decoded.FromBytesStrict(malleated, ell, common.N_BLINDERS)
+		require.ErrorIs(t, err, ErrMalformedProof)
+		require.ErrorContains(t, err, "slice has length 4294967295")
+	})
+}
+
+func TestProofJSON(t *testing.T) {
+	t.Parallel()
+
+	n := 64
+	crs, Rs, Ss, Ts, Us, M, perm, k, rs_m := setup(t, n)
+	proof, err := Prove(crs, Rs, Ss, Ts, Us, M, perm, k, rs_m, common.NewSecureRand())
+	require.NoError(t, err)
+
+	encoded, err := json.Marshal(proof)
+	require.NoError(t, err)
+
+	// Sub-arguments are nested, and scalars are 0x-prefixed hex strings.
+	var fields map[string]json.RawMessage
+	require.NoError(t, json.Unmarshal(encoded, &fields))
+	var spa map[string]json.RawMessage
+	require.NoError(t, json.Unmarshal(fields["same_permutation_proof"], &spa))
+	var gpa map[string]json.RawMessage
+	require.NoError(t, json.Unmarshal(spa["grand_product_proof"], &gpa))
+	var ipa map[string]interface{}
+	require.NoError(t, json.Unmarshal(gpa["inner_product_proof"], &ipa))
+	c0 := proof.ProofSamePermutation.GPAProof.IPAProof.C0
+	require.Equal(t, common.FrToHex(&c0), ipa["c0"])
+
+	var decoded Proof
+	require.NoError(t, json.Unmarshal(encoded, &decoded))
+	encoded2, err := json.Marshal(decoded)
+	require.NoError(t, err)
+	require.Equal(t, encoded, encoded2)
+
+	ok, err := Verify(decoded, crs, Rs, Ss, Ts, Us, M, common.NewSecureRand())
+	require.NoError(t, err)
+	require.True(t, ok)
+
+	var malformed Proof
+	require.Error(t, json.Unmarshal([]byte(strings.Replace(string(encoded), `"0x`, `"0y`, 1)), &malformed))
+}
+
+func TestNonPowerOfTwoSizes(t *testing.T) {
+	t.Parallel()
+
+	for _, ell := range []int{1, 2, 3, 5, 13, 60, 61, 100} {
+		for _, padded := range []bool{false, true} {
+			ell, padded := ell, padded
+			t.Run(fmt.Sprintf("ell=%d/padded=%t", ell, padded), func(t *testing.T) {
+				t.Parallel()
+				testNonPowerOfTwoSize(t, ell, padded)
+			})
+		}
+	}
+}
+
+func testNonPowerOfTwoSize(t *testing.T, ell int, padded bool) {
+	var crs CRS
+	var err error
+	if padded {
+		crs, err = GeneratePaddedCRSFromSeed([]byte("curdleproofs_test"), ell)
+		require.NoError(t, err)
+		n := len(crs.Gs) + common.N_BLINDERS
+		require.Zero(t, n&(n-1))
+		exact, err := GenerateCRSFromSeed([]byte("curdleproofs_test"), ell)
+		require.NoError(t, err)
+		require.Equal(t, exact.Gs, crs.Gs[:ell])
+	} else {
+		crs, err = GenerateCRSFromSeed([]byte("curdleproofs_test"), ell)
+		require.NoError(t, err)
+		require.Len(t, crs.Gs, ell)
+	}
+
+	rand := common.NewSecureRand()
+	perm, err := rand.GeneratePermutation(ell)
+	require.NoError(t, err)
+	k, err := rand.GetFr()
+	require.NoError(t, err)
+	Rs, err := rand.GetG1Affines(ell)
+	require.NoError(t, err)
+	Ss, err := rand.GetG1Affines(ell)
+	require.NoError(t, err)
+	Ts, Us, M, rs_m, err := common.ShufflePermuteCommit(crs.Gs, crs.Hs, Rs, Ss, perm, k, rand, common.Exec{})
+	require.NoError(t, err)
+	require.Len(t, Ts, ell)
+
+	proof, err := Prove(crs, Rs, Ss, Ts, Us, M, perm, k, rs_m, rand)
+	require.NoError(t, err)
+	ok, err := Verify(proof, crs, Rs, Ss, Ts, Us, M, rand)
+	require.NoError(t, err)
+	require.True(t, ok)
+
+	var decoded Proof
+	encoded, err := proof.MarshalBinary()
+	require.NoError(t, err)
+	require.NoError(t, decoded.FromBytesStrict(encoded, ell, common.N_BLINDERS))
+	size, err := ProofSize(ell, common.N_BLINDERS)
+	require.NoError(t, err)
+	require.Len(t, encoded, size)
+
+	if ell > 1 {
+		ok, err = Verify(proof, crs, Rs, Ss, append(Ts[1:ell:ell], Ts[0]), append(Us[1:ell:ell], Us[0]), M, rand)
+		require.NoError(t, err)
+		require.False(t, ok)
+	}
+	// The proof is bound to the number of elements, so it can't be
+	// reinterpreted as a proof for a shorter instance.
+	ok, err = Verify(proof, crs, Rs[:ell-1], Ss[:ell-1], Ts[:ell-1], Us[:ell-1], M, rand)
+	if ell == 1 {
+		require.ErrorIs(t, err, ErrEmptyInstance)
+	} else {
+		require.NoError(t, err)
+	}
+	require.False(t, ok)
+}
+
+func TestBlinders(t *testing.T) {
+	t.Parallel()
+
+	for _, nBlinders := range []int{4, 5, 6, 8} {
+		for _, ell := range []int{5, 13} {
+			nBlinders, ell := nBlinders, ell
+			t.Run(fmt.Sprintf("blinders=%d/ell=%d", nBlinders, ell), func(t *testing.T) {
+				t.Parallel()
+
+				crs, err := GenerateCRSFromSeedWithBlinders([]byte("curdleproofs_test"), ell, nBlinders)
+				require.NoError(t, err)
+				require.Equal(t, nBlinders, crs.NBlinders())
+				ok, err := VerifyCRSFromSeed(crs, []byte("curdleproofs_test"))
+				require.NoError(t, err)
+				require.True(t, ok)
+
+				rand := common.NewSecureRand()
+				perm, err := rand.GeneratePermutation(ell)
+				require.NoError(t, err)
+				k, err := rand.GetFr()
+				require.NoError(t, err)
+				Rs, err := rand.GetG1Affines(ell)
+				require.NoError(t, err)
+				Ss, err := rand.GetG1Affines(ell)
+				require.NoError(t, err)
+				Ts, Us, M, rs_m, err := common.ShufflePermuteCommit(crs.Gs, crs.Hs, Rs, Ss, perm, k, rand, common.Exec{})
+				require.NoError(t, err)
+				require.Len(t, rs_m, nBlinders)
+
+				// Completeness.
+				proof, err := Prove(crs, Rs, Ss, Ts, Us, M, perm, k, rs_m, rand, WithDomainSeparation(nil))
+				require.NoError(t, err)
+				ok, err = Verify(proof, crs, Rs, Ss, Ts, Us, M, rand, WithDomainSeparation(nil))
+				require.NoError(t, err)
+				require.True(t, ok)
+
+				encoded, err := proof.MarshalBinary()
+				require.NoError(t, err)
+				var decoded Proof
+				require.NoError(t, decoded.FromBytesStrict(encoded, ell, nBlinders))
+				size, err := ProofSize(ell, nBlinders)
+				require.NoError(t, err)
+				require.Len(t, encoded, size)
+
+				// Soundness.
+				ok, err = Verify(proof, crs, Ss, Rs, Ts, Us, M, rand, WithDomainSeparation(nil))
+				require.NoError(t, err)
+				require.False(t, ok)
+				ok, err = Verify(proof, crs, Rs, Ss, append(Ts[1:ell:ell], Ts[0]), append(Us[1:ell:ell], Us[0]), M, rand, WithDomainSeparation(nil))
+				require.NoError(t, err)
+				require.False(t, ok)
+			})
+		}
+	}
+
+	t.Run("invalid proof size params", func(t *testing.T) {
+		_, err := ProofSize(0, common.N_BLINDERS)
+		require.Error(t, err)
+		_, err = ProofSize(6, MinBlinders-1)
+		require.Error(t, err)
+	})
+
+	t.Run("too few blinders", func(t *testing.T) {
+		// With 2 blinders A isn't blinded at all, and with 3 a single blinder is
+		// left to hide it.
+		for _, nBlinders := range []int{2, 3} {
+			_, err := GenerateCRSWithBlinders(6, nBlinders, common.NewSecureRand())
+			require.ErrorContains(t, err, "at least")
+			_, err = GenerateCRSFromSeedWithBlinders([]byte("curdleproofs_test"), 5, nBlinders)
+			require.ErrorContains(t, err, "at least")
+
+			crs, err := GenerateCRS(4, common.NewSecureRand())
+			require.NoError(t, err)
+			crs.Hs = crs.Hs[:nBlinders]
+			require.ErrorContains(t, crs.Validate(), "at least")
+		}
+	})
+}
+
+type failingWriter struct{}
+
+func (failingWriter) Write([]byte) (int, error) {
+	return 0, fmt.Errorf("write failed")
+}
@@ -0,0 +1,20 @@
+package curdleproof
+
+import (
+	"errors"
+
+	"github.com/jsign/curdleproofs/common"
+)
+
+// Errors returned when the inputs are malformed, as opposed to a well-formed
+// proof being rejected, which is signaled by returning false.
+var (
+	ErrEmptyInstance   = errors.New("empty instance")
+	ErrLengthMismatch  = errors.New("instance vectors have different lengths")
+	ErrCRSSizeMismatch = errors.New("instance size doesn't match the crs size")
+	ErrIdentityPoint   = errors.New("instance contains the identity point")
+	// ErrMalformedProof is wrapped by the errors of decoding a proof that
+	// isn't a valid encoding, and of verifying one whose vectors have the
+	// wrong length.
+	ErrMalformedProof = common.ErrMalformedProof
+)
@@ -1,6 +1,7 @@
 package grandproductargument
 
 import (
+	"encoding/json"
 	"fmt"
 	"io"
 	"math/big"
@@ -30,6 +31,16 @@ type CRS struct {
 	Gs []bls12381.G1Affine
 	Hs []bls12381.G1Affine
 	H  bls12381.G1Jac
+
+	// GsHs optionally caches Gs || Hs. If nil, it's computed when needed.
+	GsHs []bls12381.G1Affine
+
+	// GsBasis, GsHsBasis and GsHsHBasis optionally hold Gs, Gs || Hs and
+	// Gs || Hs || H registered in the table of the verifier's MSM accumulator.
+	// If nil, the points are accumulated by value.
+	GsBasis    *msmaccumulator.Basis
+	GsHsBasis  *msmaccumulator.Basis
+	GsHsHBasis *msmaccumulator.Basis
 }
 
 var (
@@ -46,7 +57,8 @@ func Prove(
 	bs []fr.Element,
 	r_bs []fr.Element,
 	transcript *transcript.Transcript,
-	rand *common.Rand,
+	rand common.Rand,
+	exec common.Exec,
 ) (Proof, error) {
 	// Step 1.
 	transcript.AppendPoints(labelGprodStep1, B)
@@ -64,10 +76,10 @@ func Prove(
 		return Proof{}, fmt.Errorf("generate R_Cs: %s", err)
 	}
 	var C, C_L, C_R bls12381.G1Jac
-	if _, err := C_L.MultiExp(crs.Gs, cs, common.MultiExpConf); err != nil {
+	if _, err := C_L.MultiExp(crs.Gs, cs, exec.MultiExpConf()); err != nil {
 		return Proof{}, fmt.Errorf("compute C_L: %s", err)
 	}
-	if _, err := C_R.MultiExp(crs.Hs, r_cs, common.MultiExpConf); err != nil {
+	if _, err := C_R.MultiExp(crs.Hs, r_cs, exec.MultiExpConf()); err != nil {
 		return Proof{}, fmt.Errorf("compute C_R: %s", err)
 	}
 	C.Set(&C_L).AddAssign(&C_R)
@@ -88,19 +100,26 @@ func Prove(
 		return Proof{}, fmt.Errorf("beta is zero")
 	}
 	// Step 3
+	if err := exec.Err(); err != nil {
+		return Proof{}, err
+	}
 	var betaInv fr.Element
 	betaInv.Inverse(&beta)
 
-	Gs_prime := make([]bls12381.G1Affine, len(crs.Gs))
+	// Gs_prime and Hs_prime are computed together, with a single batch inversion.
+	GsHs := crs.gsHs()
+	scalars := make([]fr.Element, len(GsHs))
 	tmpBetaInv := betaInv
-	for i := range Gs_prime {
-		Gs_prime[i].ScalarMultiplication(&crs.Gs[i], common.FrToBigInt(&tmpBetaInv))
+	for i := range crs.Gs {
+		scalars[i] = tmpBetaInv
 		tmpBetaInv.Mul(&tmpBetaInv, &betaInv)
 	}
-	Hs_prime := make([]bls12381.G1Affine, len(crs.Hs))
-	for i := range Hs_prime {
-		Hs_prime[i].ScalarMultiplication(&crs.Hs[i], common.FrToBigInt(&tmpBetaInv))
+	for i := len(crs.Gs); i < len(scalars); i++ {
+		scalars[i] = tmpBetaInv
 	}
+	GsHs_prime := make([]bls12381.G1Affine, len(GsHs))
+	common.ScaleG1(exec, GsHs_prime, GsHs, scalars)
+	Gs_prime, Hs_prime := GsHs_prime[:len(crs.Gs)], GsHs_prime[len(crs.Gs):]
 	bs_prime := make([]fr.Element, len(crs.Gs))
 	tmpBeta := beta
 	for i := range bs_prime {
@@ -129,20 +148,20 @@ func Prove(
 		alphaBetaExpPlus1[i].Mul(&alpha, &betaExpLPlus1)
 	}
 	var D, D_L, D_R bls12381.G1Jac
-	if _, err := D_L.MultiExp(Gs_prime, betaPowers, common.MultiExpConf); err != nil {
+	if _, err := D_L.MultiExp(Gs_prime, betaPowers, exec.MultiExpConf()); err != nil {
 		return Proof{}, fmt.Errorf("compute D_L: %s", err)
 	}
-	if _, err := D_R.MultiExp(Hs_prime, alphaBetaExpPlus1, common.MultiExpConf); err != nil {
+	if _, err := D_R.MultiExp(Hs_prime, alphaBetaExpPlus1, exec.MultiExpConf()); err != nil {
 		return Proof{}, fmt.Errorf("compute D_R: %s", err)
 	}
 	D.Set(&B).SubAssign(&D_L).AddAssign(&D_R)
 
 	// Step 4
-	Gs := make([]bls12381.G1Affine, len(crs.Gs)+len(crs.Hs))
-	copy(Gs, crs.Gs)
-	copy(Gs[len(crs.Gs):], crs.Hs)
-
-	Gs_prime = append(Gs_prime, Hs_prime...)
+	if err := exec.Err(); err != nil {
+		return Proof{}, err
+	}
+	Gs := GsHs
+	Gs_prime = GsHs_prime
 
 	var z, z_L, z_R fr.Element
 	z_L.Mul(&r_p, &betaExpLPlus1)
@@ -162,14 +181,14 @@ func Prove(
 		return Proof{}, fmt.Errorf("IPA(C, D) != z")
 	}
 	var msmG_cs bls12381.G1Jac
-	if _, err := msmG_cs.MultiExp(Gs, cs, common.MultiExpConf); err != nil {
+	if _, err := msmG_cs.MultiExp(Gs, cs, exec.MultiExpConf()); err != nil {
 		return Proof{}, fmt.Errorf("compute msm(G, c): %s", err)
 	}
 	if !msmG_cs.Equal(&C) {
 		return Proof{}, fmt.Errorf("msm(G, c) != C")
 	}
 	var msmG_prime_ds bls12381.G1Jac
-	if _, err := msmG_prime_ds.MultiExp(Gs_prime, ds, common.MultiExpConf); err != nil {
+	if _, err := msmG_prime_ds.MultiExp(Gs_prime, ds, exec.MultiExpConf()); err != nil {
 		return Proof{}, fmt.Errorf("compute msm(G', d): %s", err)
 	}
 	if !msmG_prime_ds.Equal(&D) {
@@ -191,9 +210,10 @@ func Prove(
 		ds,
 		transcript,
 		rand,
+		exec,
 	)
 	if err != nil {
-		return Proof{}, fmt.Errorf("inner product proof: %s", err)
+		return Proof{}, fmt.Errorf("inner product proof: %w", err)
 	}
 
 	return Proof{
@@ -213,7 +233,8 @@ func Verify(
 	numBlinders int,
 	transcript *transcript.Transcript,
 	msmAccumulator *msmaccumulator.MsmAccumulator,
-	rand *common.Rand,
+	rand common.Rand,
+	exec common.Exec,
 ) (bool, error) {
 	// Step 1
 	transcript.AppendPoints(labelGprodStep1, B)
@@ -246,9 +267,7 @@ func Verify(
 	D.FromJacobian(&B).Sub(&D, &D_M).Add(&D, &D_R)
 
 	// Step 4
-	Gs := make([]bls12381.G1Affine, len(crs.Gs)+len(crs.Hs))
-	copy(Gs, crs.Gs)
-	copy(Gs[len(crs.Gs):], crs.Hs)
+	Gs := crs.gsHs()
 
 	var z, z_L, z_M fr.Element
 	var betaExpL, betaExpLPlusOne fr.Element
@@ -262,7 +281,9 @@ func Verify(
 	ipaCRS := innerproductargument.CRS{
 		Gs: Gs,
 		// TODO(jsign): not using Gs_prime, reconsider.
-		H: crs.H,
+		H:        crs.H,
+		GsBasis:  crs.GsHsBasis,
+		GsHBasis: crs.GsHsHBasis,
 	}
 
 	var DAffine bls12381.G1Jac
@@ -277,30 +298,67 @@ func Verify(
 		transcript,
 		msmAccumulator,
 		rand,
+		exec,
 	)
 	if err != nil {
-		return false, fmt.Errorf("inner product proof verification: %s", err)
+		return false, fmt.Errorf("inner product proof verification: %w", err)
 	}
 
 	return ok, nil
 }
 
+func (crs *CRS) gsHs() []bls12381.G1Affine {
+	if crs.GsHs != nil {
+		return crs.GsHs
+	}
+	Gs := make([]bls12381.G1Affine, len(crs.Gs)+len(crs.Hs))
+	copy(Gs, crs.Gs)
+	copy(Gs[len(crs.Gs):], crs.Hs)
+	return Gs
+}
+
 func (p *Proof) FromReader(r io.Reader) error {
 	d := bls12381.NewDecoder(r)
 	var tmp bls12381.G1Affine
 	if err := d.Decode(&tmp); err != nil {
-		return fmt.Errorf("decode C: %s", err)
+		return fmt.Errorf("%w: decode C: %s", common.ErrMalformedProof, err)
 	}
 	p.C.FromAffine(&tmp)
 	if err := d.Decode(&p.Rp); err != nil {
-		return fmt.Errorf("decode Rp: %s", err)
+		return fmt.Errorf("%w: decode Rp: %s", common.ErrMalformedProof, err)
 	}
 	if err := p.IPAProof.FromReader(r); err != nil {
-		return fmt.Errorf("decode IPAProof: %s", err)
+		return fmt.Errorf("decode IPAProof: %w", err)
+	}
+	return nil
+}
+
+// FromReaderStrict is like FromReader but only accepts the canonical encoding of
+// a proof for vectors of size n.
+func (p *Proof) FromReaderStrict(r io.Reader, n int) error {
+	d := common.NewStrictDecoder(r)
+	if err := d.DecodeG1Jac(&p.C); err != nil {
+		return fmt.Errorf("%w: decode C: %s", common.ErrMalformedProof, err)
+	}
+	if err := d.DecodeFr(&p.Rp); err != nil {
+		return fmt.Errorf("%w: decode Rp: %s", common.ErrMalformedProof, err)
+	}
+	if err := p.IPAProof.FromReaderStrict(r, n); err != nil {
+		return fmt.Errorf("decode IPAProof: %w", err)
 	}
 	return nil
 }
 
+// ProofSize returns the size of the encoding of a proof for vectors of size n.
+func ProofSize(n int) (int, error) {
+	ipaSize, err := innerproductargument.ProofSize(n)
+	if err != nil {
+		return 0, err
+	}
+	// C, Rp and IPAProof.
+	return bls12381.SizeOfG1AffineCompressed + fr.Bytes + ipaSize, nil
+}
+
 func (p *Proof) Serialize(w io.Writer) error {
 	var cAffine bls12381.G1Affine
 	cAffine.FromJacobian(&p.C)
@@ -316,3 +374,34 @@ func (p *Proof) Serialize(w io.Writer) error {
 	}
 	return nil
 }
+
+type proofJSON struct {
+	C        string                     `json:"C"`
+	Rp       string                     `json:"r_p"`
+	IPAProof innerproductargument.Proof `json:"inner_product_proof"`
+}
+
+// MarshalJSON encodes the proof with 0x-prefixed compressed points and scalars.
+func (p Proof) MarshalJSON() ([]byte, error) {
+	return json.Marshal(proofJSON{
+		C:        common.G1JacToHex(&p.C),
+		Rp:       common.FrToHex(&p.Rp),
+		IPAProof: p.IPAProof,
+	})
+}
+
+func (p *Proof) UnmarshalJSON(data []byte) error {
+	var pj proofJSON
+	if err := json.Unmarshal(data, &pj); err != nil {
+		return err
+	}
+	var err error
+	if p.C, err = common.G1JacFromHex(pj.C); err != nil {
+		return fmt.Errorf("%w: decode C: %s", common.ErrMalformedProof, err)
+	}
+	if p.Rp, err = common.FrFromHex(pj.Rp); err != nil {
+		return fmt.Errorf("%w: decode Rp: %s", common.ErrMalformedProof, err)
+	}
+	p.IPAProof = pj.IPAProof
+	return nil
+}
@@ -16,7 +16,7 @@ func TestCompletenessAndSoundess(t *testing.T) {
 	t.Parallel()
 
 	n := 128
-	rand, err := common.NewRand(0)
+	rand, err := common.NewTestingRand(0)
 	require.NoError(t, err)
 
 	var proof Proof
@@ -46,9 +46,9 @@ func TestCompletenessAndSoundess(t *testing.T) {
 		}
 
 		var B, B_L, B_R bls12381.G1Jac
-		_, err = B_L.MultiExp(crsGs, bs, common.MultiExpConf)
+		_, err = B_L.MultiExp(crsGs, bs, common.Exec{}.MultiExpConf())
 		require.NoError(t, err)
-		_, err = B_R.MultiExp(crsHs, r_bs, common.MultiExpConf)
+		_, err = B_R.MultiExp(crsHs, r_bs, common.Exec{}.MultiExpConf())
 		require.NoError(t, err)
 		B.AddAssign(&B_L).AddAssign(&B_R)
 
@@ -60,6 +60,7 @@ func TestCompletenessAndSoundess(t *testing.T) {
 			r_bs,
 			transcriptProver,
 			rand,
+			common.Exec{},
 		)
 		require.NoError(t, err)
 	}
@@ -77,11 +78,12 @@ func TestCompletenessAndSoundess(t *testing.T) {
 			transcriptVerifier,
 			msmAccumulator,
 			rand,
+			common.Exec{},
 		)
 		require.NoError(t, err)
 		require.True(t, ok)
 
-		ok, err = msmAccumulator.Verify()
+		ok, err = msmAccumulator.Verify(common.Exec{})
 		require.NoError(t, err)
 		require.True(t, ok)
 	})
@@ -102,11 +104,12 @@ func TestCompletenessAndSoundess(t *testing.T) {
 			transcriptVerifier,
 			msmAccumulator,
 			rand,
+			common.Exec{},
 		)
 		require.NoError(t, err)
 		require.True(t, ok) // This is OK, because the ultimate check is in the MSM accumulator below.
 
-		ok, err = msmAccumulator.Verify()
+		ok, err = msmAccumulator.Verify(common.Exec{})
 		require.NoError(t, err)
 		require.False(t, ok) // Note we expect this to be false.
 	})
@@ -128,11 +131,12 @@ func TestCompletenessAndSoundess(t *testing.T) {
 			transcriptVerifier,
 			msmAccumulator,
 			rand,
+			common.Exec{},
 		)
 		require.NoError(t, err)
 		require.True(t, ok) // This is OK, because the ultimate check is in the MSM accumulator below.
 
-		ok, err = msmAccumulator.Verify()
+		ok, err = msmAccumulator.Verify(common.Exec{})
 		require.NoError(t, err)
 		require.False(t, ok) // Note we expect this to be false.
 	})
@@ -154,7 +158,7 @@ func TestCompletenessAndSoundess(t *testing.T) {
 
 // TOOD(jsign): replicat in other tests.
 func genVerifierParameters(t *testing.T, n int) (CRS, bls12381.G1Affine, bls12381.G1Affine, bls12381.G1Jac, fr.Element, *transcript.Transcript, *msmaccumulator.MsmAccumulator) {
-	rand, err := common.NewRand(0)
+	rand, err := common.NewTestingRand(0)
 	require.NoError(t, err)
 
 	transcriptVerifier := transcript.New([]byte("gprod"))
@@ -190,9 +194,9 @@ func genVerifierParameters(t *testing.T, n int) (CRS, bls12381.G1Affine, bls1238
 	}
 
 	var B, B_L, B_R bls12381.G1Jac
-	_, err = B_L.MultiExp(crsGs, bs, common.MultiExpConf)
+	_, err = B_L.MultiExp(crsGs, bs, common.Exec{}.MultiExpConf())
 	require.NoError(t, err)
-	_, err = B_R.MultiExp(crsHs, r_bs, common.MultiExpConf)
+	_, err = B_R.MultiExp(crsHs, r_bs, common.Exec{}.MultiExpConf())
 	require.NoError(t, err)
 	B.AddAssign(&B_L).AddAssign(&B_R)
 
@@ -1,6 +1,7 @@
 package groupcommitment
 
 import (
+	"encoding/json"
 	"fmt"
 	"io"
 
@@ -56,18 +57,33 @@ func (gc *GroupCommitment) FromReader(r io.Reader) error {
 	var tmp bls12381.G1Affine
 
 	if err := d.Decode(&tmp); err != nil {
-		return fmt.Errorf("decoding T_1: %s", err)
+		return fmt.Errorf("%w: decoding T_1: %s", common.ErrMalformedProof, err)
 	}
 	gc.T_1.FromAffine(&tmp)
 
 	if err := d.Decode(&tmp); err != nil {
-		return fmt.Errorf("decoding T_2: %s", err)
+		return fmt.Errorf("%w: decoding T_2: %s", common.ErrMalformedProof, err)
 	}
 	gc.T_2.FromAffine(&tmp)
 
 	return nil
 }
 
+// FromReaderStrict is like FromReader but only accepts the canonical encoding.
+func (gc *GroupCommitment) FromReaderStrict(r io.Reader) error {
+	d := common.NewStrictDecoder(r)
+	if err := d.DecodeG1Jac(&gc.T_1); err != nil {
+		return fmt.Errorf("%w: decoding T_1: %s", common.ErrMalformedProof, err)
+	}
+	if err := d.DecodeG1Jac(&gc.T_2); err != nil {
+		return fmt.Errorf("%w: decoding T_2: %s", common.ErrMalformedProof, err)
+	}
+	return nil
+}
+
+// Size is the size of the encoding of a group commitment.
+const Size = 2 * bls12381.SizeOfG1AffineCompressed
+
 func (gc *GroupCommitment) Serialize(w io.Writer) error {
 	ts := bls12381.BatchJacobianToAffineG1([]bls12381.G1Jac{gc.T_1, gc.T_2})
 	e := bls12381.NewEncoder(w)
@@ -79,3 +95,29 @@ func (gc *GroupCommitment) Serialize(w io.Writer) error {
 	}
 	return nil
 }
+
+type groupCommitmentJSON struct {
+	T_1 string `json:"T_1"`
+	T_2 string `json:"T_2"`
+}
+
+// MarshalJSON encodes the commitment with 0x-prefixed compressed points.
+func (gc GroupCommitment) MarshalJSON() ([]byte, error) {
+	ts := common.G1JacsToHex([]bls12381.G1Jac{gc.T_1, gc.T_2})
+	return json.Marshal(groupCommitmentJSON{T_1: ts[0], T_2: ts[1]})
+}
+
+func (gc *GroupCommitment) UnmarshalJSON(data []byte) error {
+	var gcj groupCommitmentJSON
+	if err := json.Unmarshal(data, &gcj); err != nil {
+		return err
+	}
+	var err error
+	if gc.T_1, err = common.G1JacFromHex(gcj.T_1); err != nil {
+		return fmt.Errorf("%w: decoding T_1: %s", common.ErrMalformedProof, err)
+	}
+	if gc.T_2, err = common.G1JacFromHex(gcj.T_2); err != nil {
+		return fmt.Errorf("%w: decoding T_2: %s", common.ErrMalformedProof, err)
+	}
+	return nil
+}
@@ -2,6 +2,7 @@ package groupcommitment
 
 import (
 	"bytes"
+	"encoding/json"
 	"testing"
 
 	bls12381 "github.com/consensys/gnark-crypto/ecc/bls12-381"
@@ -38,4 +39,12 @@ func TestEncodeDecode(t *testing.T) {
 	require.NoError(t, gc2.Serialize(buf2))
 
 	require.Equal(t, expected, buf2.Bytes())
+
+	encoded, err := json.Marshal(gc)
+	require.NoError(t, err)
+	require.JSONEq(t, `{"T_1":"`+common.G1AffineToHex(&t1)+`","T_2":"`+common.G1AffineToHex(&t2)+`"}`, string(encoded))
+
+	var gc3 GroupCommitment
+	require.NoError(t, json.Unmarshal(encoded, &gc3))
+	require.True(t, gc.Eq(&gc3))
 }
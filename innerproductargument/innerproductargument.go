@@ -1,10 +1,12 @@
 package innerproductargument
 
 import (
+	"encoding/json"
 	"fmt"
 	"io"
 	"math/bits"
 
+	"github.com/consensys/gnark-crypto/ecc"
 	bls12381 "github.com/consensys/gnark-crypto/ecc/bls12-381"
 	"github.com/consensys/gnark-crypto/ecc/bls12-381/fr"
 	"github.com/jsign/curdleproofs/common"
@@ -24,6 +26,12 @@ type CRS struct {
 	Gs       []bls12381.G1Affine
 	Gs_prime []bls12381.G1Affine
 	H        bls12381.G1Jac
+
+	// GsBasis and GsHBasis optionally hold Gs and Gs || H registered in the table
+	// of the verifier's MSM accumulator. If nil, Verify accumulates the points
+	// by value.
+	GsBasis  *msmaccumulator.Basis
+	GsHBasis *msmaccumulator.Basis
 }
 
 type Proof struct {
@@ -35,8 +43,8 @@ type Proof struct {
 	L_Ds []bls12381.G1Jac
 	R_Ds []bls12381.G1Jac
 
-	c0 fr.Element
-	d0 fr.Element
+	C0 fr.Element
+	D0 fr.Element
 }
 
 func Prove(
@@ -47,7 +55,8 @@ func Prove(
 	cs []fr.Element,
 	ds []fr.Element,
 	transcript *transcript.Transcript,
-	rand *common.Rand,
+	rand common.Rand,
+	exec common.Exec,
 ) (Proof, error) {
 	if len(cs) != len(ds) {
 		return Proof{}, fmt.Errorf("cs and ds are not the same length")
@@ -62,13 +71,22 @@ func Prove(
 		return Proof{}, fmt.Errorf("generate IPA blinders: %s", err)
 	}
 
-	var B_c bls12381.G1Jac
-	if _, err := B_c.MultiExp(crs.Gs, rs_c, common.MultiExpConf); err != nil {
-		return Proof{}, fmt.Errorf("multiexp B_c: %s", err)
-	}
-	var B_d bls12381.G1Jac
-	if _, err := B_d.MultiExp(crs.Gs_prime, rs_d, common.MultiExpConf); err != nil {
-		return Proof{}, fmt.Errorf("multiexp B_d: %s", err)
+	var B_c, B_d bls12381.G1Jac
+	if err := exec.Parallel(
+		func(conf ecc.MultiExpConfig) error {
+			if _, err := B_c.MultiExp(crs.Gs, rs_c, conf); err != nil {
+				return fmt.Errorf("multiexp B_c: %s", err)
+			}
+			return nil
+		},
+		func(conf ecc.MultiExpConfig) error {
+			if _, err := B_d.MultiExp(crs.Gs_prime, rs_d, conf); err != nil {
+				return fmt.Errorf("multiexp B_d: %s", err)
+			}
+			return nil
+		},
+	); err != nil {
+		return Proof{}, err
 	}
 
 	transcript.AppendPoints(labelStep1, C, D)
@@ -97,7 +115,14 @@ func Prove(
 	L_Ds := make([]bls12381.G1Jac, 0, m)
 	R_Ds := make([]bls12381.G1Jac, 0, m)
 
+	// The bases are folded into fresh buffers, so the CRS isn't mutated.
+	G_folded := make([]bls12381.G1Affine, n/2)
+	G_prime_folded := make([]bls12381.G1Affine, n/2)
+
 	for len(cs) > 1 {
+		if err := exec.Err(); err != nil {
+			return Proof{}, err
+		}
 		n /= 2
 
 		c_L, c_R := common.SplitAt(cs, n)
@@ -105,38 +130,49 @@ func Prove(
 		G_L, G_R := common.SplitAt(crs.Gs, n)
 		G_prime_L, G_prime_R := common.SplitAt(crs.Gs_prime, n)
 
-		var L_C, L_C_L, L_C_R bls12381.G1Jac
-		if _, err := L_C_L.MultiExp(G_R, c_L, common.MultiExpConf); err != nil {
-			return Proof{}, fmt.Errorf("ipa L_C_1 multiexp: %s", err)
-		}
-		ipaCLDR, err := common.IPA(c_L, d_R)
-		if err != nil {
-			return Proof{}, fmt.Errorf("ipa L_C_2 multiexp: %s", err)
-		}
-		L_C_R.ScalarMultiplication(&H, common.FrToBigInt(&ipaCLDR))
-		L_C.Set(&L_C_L)
-		L_C.AddAssign(&L_C_R)
-
-		var L_D bls12381.G1Jac
-		if _, err := L_D.MultiExp(G_prime_L, d_R, common.MultiExpConf); err != nil {
-			return Proof{}, fmt.Errorf("ipa L_D multiexp: %s", err)
-		}
-
-		var R_C, R_C_L, R_C_R bls12381.G1Jac
-		if _, err := R_C_L.MultiExp(G_L, c_R, common.MultiExpConf); err != nil {
-			return Proof{}, fmt.Errorf("ipa R_C_1 multiexp: %s", err)
-		}
-		ipaCRDL, err := common.IPA(c_R, d_L)
-		if err != nil {
-			return Proof{}, fmt.Errorf("ipa R_C_2 multiexp: %s", err)
-		}
-		R_C_R.ScalarMultiplication(&H, common.FrToBigInt(&ipaCRDL))
-		R_C.Set(&R_C_L)
-		R_C.AddAssign(&R_C_R)
-
-		var R_D bls12381.G1Jac
-		if _, err := R_D.MultiExp(G_prime_R, d_L, common.MultiExpConf); err != nil {
-			return Proof{}, fmt.Errorf("ipa R_D multiexp: %s", err)
+		// L_C, L_D, R_C and R_D are independent, so they're computed concurrently.
+		var L_C, L_D, R_C, R_D bls12381.G1Jac
+		if err := exec.Parallel(
+			func(conf ecc.MultiExpConfig) error {
+				var L_C_R bls12381.G1Jac
+				if _, err := L_C.MultiExp(G_R, c_L, conf); err != nil {
+					return fmt.Errorf("ipa L_C_1 multiexp: %s", err)
+				}
+				ipaCLDR, err := common.IPA(c_L, d_R)
+				if err != nil {
+					return fmt.Errorf("ipa L_C_2 multiexp: %s", err)
+				}
+				L_C_R.ScalarMultiplication(&H, common.FrToBigInt(&ipaCLDR))
+				L_C.AddAssign(&L_C_R)
+				return nil
+			},
+			func(conf ecc.MultiExpConfig) error {
+				if _, err := L_D.MultiExp(G_prime_L, d_R, conf); err != nil {
+					return fmt.Errorf("ipa L_D multiexp: %s", err)
+				}
+				return nil
+			},
+			func(conf ecc.MultiExpConfig) error {
+				var R_C_R bls12381.G1Jac
+				if _, err := R_C.MultiExp(G_L, c_R, conf); err != nil {
+					return fmt.Errorf("ipa R_C_1 multiexp: %s", err)
+				}
+				ipaCRDL, err := common.IPA(c_R, d_L)
+				if err != nil {
+					return fmt.Errorf("ipa R_C_2 multiexp: %s", err)
+				}
+				R_C_R.ScalarMultiplication(&H, common.FrToBigInt(&ipaCRDL))
+				R_C.AddAssign(&R_C_R)
+				return nil
+			},
+			func(conf ecc.MultiExpConfig) error {
+				if _, err := R_D.MultiExp(G_prime_R, d_L, conf); err != nil {
+					return fmt.Errorf("ipa R_D multiexp: %s", err)
+				}
+				return nil
+			},
+		); err != nil {
+			return Proof{}, err
 		}
 
 		L_Cs = append(L_Cs, L_C)
@@ -156,19 +192,17 @@ func Prove(
 			var tmps fr.Element
 			c_L[i].Add(&c_L[i], tmps.Mul(&gamma_inv, &c_R[i]))
 			d_L[i].Add(&d_L[i], tmps.Mul(&gamma, &d_R[i]))
-
-			var tmpp bls12381.G1Affine
-			tmpp.ScalarMultiplication(&G_R[i], common.FrToBigInt(&gamma))
-			G_L[i].Add(&G_L[i], &tmpp)
-
-			tmpp.ScalarMultiplication(&G_prime_R[i], common.FrToBigInt(&gamma_inv))
-			G_prime_L[i].Add(&G_prime_L[i], &tmpp)
 		}
+		common.FoldG1(
+			exec,
+			common.G1Fold{Out: G_folded[:n], L: G_L, R: G_R, Scalar: common.FrToBigInt(&gamma)},
+			common.G1Fold{Out: G_prime_folded[:n], L: G_prime_L, R: G_prime_R, Scalar: common.FrToBigInt(&gamma_inv)},
+		)
 
 		cs = c_L
 		ds = d_L
-		crs.Gs = G_L
-		crs.Gs_prime = G_prime_L
+		crs.Gs = G_folded[:n]
+		crs.Gs_prime = G_prime_folded[:n]
 	}
 
 	if len(cs) != 1 || len(ds) != 1 || len(crs.Gs) != 1 || len(crs.Gs_prime) != 1 {
@@ -182,8 +216,8 @@ func Prove(
 		R_Cs: R_Cs,
 		L_Ds: L_Ds,
 		R_Ds: R_Ds,
-		c0:   cs[0],
-		d0:   ds[0],
+		C0:   cs[0],
+		D0:   ds[0],
 	}, nil
 }
 
@@ -196,7 +230,8 @@ func Verify(
 	us []fr.Element,
 	transcript *transcript.Transcript,
 	msmAccumulator *msmaccumulator.MsmAccumulator,
-	rand *common.Rand,
+	rand common.Rand,
+	exec common.Exec,
 ) (bool, error) {
 	// Step 1.
 	transcript.AppendPoints(labelStep1, C, D)
@@ -211,6 +246,9 @@ func Verify(
 		return false, fmt.Errorf("ipa n is not a power of two")
 	}
 	m := bits.Len(uint(n)) - 1
+	if len(proof.L_Cs) != m || len(proof.L_Ds) != m || len(proof.R_Cs) != m || len(proof.R_Ds) != m {
+		return false, fmt.Errorf("%w: ipa proof vectors must have length %d", common.ErrMalformedProof, m)
+	}
 
 	gamma := make([]fr.Element, 0, m)
 	for i := 0; i < m; i++ {
@@ -220,22 +258,12 @@ func Verify(
 	gamma_inv := fr.BatchInvert(gamma)
 
 	// Step 3.
-	s := make([]fr.Element, n)
-	s_prime := make([]fr.Element, n)
-	for i := 0; i < n; i++ {
-		s[i] = fr.One()
-		s_prime[i] = fr.One()
-		for j := 0; j < m; j++ {
-			if i&(1<<j) != 0 {
-				s[i].Mul(&s[i], &gamma[m-j-1])
-				s_prime[i].Mul(&s_prime[i], &gamma_inv[m-j-1])
-			}
-		}
-	}
+	s := common.FoldingScalars(gamma)
+	s_prime := common.FoldingScalars(gamma_inv)
 
 	// Accummulate check 1
 	var AC1, AC1_L, AC1_M_1, AC1_M_2, AC1_M_3, AC1_R bls12381.G1Jac
-	if _, err := AC1_L.MultiExp(bls12381.BatchJacobianToAffineG1(proof.L_Cs), gamma, common.MultiExpConf); err != nil {
+	if _, err := AC1_L.MultiExp(bls12381.BatchJacobianToAffineG1(proof.L_Cs), gamma, exec.MultiExpConf()); err != nil {
 		return false, fmt.Errorf("ipa AC1_L multiexp: %s", err)
 	}
 	AC1_M_1.Set(&proof.B_c)
@@ -246,7 +274,7 @@ func Verify(
 	var betaH bls12381.G1Jac
 	betaH.ScalarMultiplication(&crs.H, common.FrToBigInt(&beta))
 	AC1_M_3.ScalarMultiplication(&betaH, common.FrToBigInt(&alphasquaredtimesz))
-	if _, err := AC1_R.MultiExp(bls12381.BatchJacobianToAffineG1(proof.R_Cs), gamma_inv, common.MultiExpConf); err != nil {
+	if _, err := AC1_R.MultiExp(bls12381.BatchJacobianToAffineG1(proof.R_Cs), gamma_inv, exec.MultiExpConf()); err != nil {
 		return false, fmt.Errorf("ipa AC1_R multiexp: %s", err)
 	}
 	AC1.Set(&AC1_L)
@@ -254,30 +282,32 @@ func Verify(
 	AC1.AddAssign(&AC1_M_2)
 	AC1.AddAssign(&AC1_M_3)
 	AC1.AddAssign(&AC1_R)
-	GplusH := make([]bls12381.G1Affine, len(crs.Gs)+1)
-	copy(GplusH, crs.Gs)
-	var HAffine bls12381.G1Affine
-	HAffine.FromJacobian(&crs.H)
-	GplusH[len(crs.Gs)].Set(&HAffine)
+	GplusH := crs.GsHBasis
+	if GplusH == nil {
+		points := make([]bls12381.G1Affine, len(crs.Gs)+1)
+		copy(points, crs.Gs)
+		points[len(crs.Gs)].FromJacobian(&crs.H)
+		GplusH = msmaccumulator.NewBasis(points)
+	}
 	for i := range s {
-		s[i].Mul(&s[i], &proof.c0)
+		s[i].Mul(&s[i], &proof.C0)
 	}
-	beta.Mul(&beta, &proof.d0)
-	beta.Mul(&beta, &proof.c0)
+	beta.Mul(&beta, &proof.D0)
+	beta.Mul(&beta, &proof.C0)
 	scalars := append(s, beta)
 
-	if err := msmAccumulator.AccumulateCheck(AC1, scalars, GplusH, rand); err != nil {
+	if err := msmAccumulator.AccumulateCheck("ipa check 1", AC1, scalars, GplusH, rand); err != nil {
 		return false, fmt.Errorf("accumulate check 1: %s", err)
 	}
 
 	// Accummulate check 2
 	var AC2, AC2_L, AC2_M_1, AC2_M_2, AC2_R bls12381.G1Jac
-	if _, err := AC2_L.MultiExp(bls12381.BatchJacobianToAffineG1(proof.L_Ds), gamma, common.MultiExpConf); err != nil {
+	if _, err := AC2_L.MultiExp(bls12381.BatchJacobianToAffineG1(proof.L_Ds), gamma, exec.MultiExpConf()); err != nil {
 		return false, fmt.Errorf("multiexp: %s", err)
 	}
 	AC2_M_1.Set(&proof.B_d)
 	AC2_M_2.ScalarMultiplication(&D, common.FrToBigInt(&alpha))
-	if _, err := AC2_R.MultiExp(bls12381.BatchJacobianToAffineG1(proof.R_Ds), gamma_inv, common.MultiExpConf); err != nil {
+	if _, err := AC2_R.MultiExp(bls12381.BatchJacobianToAffineG1(proof.R_Ds), gamma_inv, exec.MultiExpConf()); err != nil {
 		return false, fmt.Errorf("multiexp: %s", err)
 	}
 	AC2.Set(&AC2_L)
@@ -287,16 +317,20 @@ func Verify(
 	scalars = s_prime
 	for i := range s_prime {
 		scalars[i].Mul(&scalars[i], &us[i])
-		scalars[i].Mul(&scalars[i], &proof.d0)
+		scalars[i].Mul(&scalars[i], &proof.D0)
 	}
-	if err := msmAccumulator.AccumulateCheck(AC2, scalars, crs.Gs, rand); err != nil {
-		return false, fmt.Errorf("accumulate check 1: %s", err)
+	Gs := crs.GsBasis
+	if Gs == nil {
+		Gs = msmaccumulator.NewBasis(crs.Gs)
+	}
+	if err := msmAccumulator.AccumulateCheck("ipa check 2", AC2, scalars, Gs, rand); err != nil {
+		return false, fmt.Errorf("accumulate check 2: %s", err)
 	}
 
 	return true, nil
 }
 
-func generateIPABlinders(rand *common.Rand, cs []fr.Element, ds []fr.Element) ([]fr.Element, []fr.Element, error) {
+func generateIPABlinders(rand common.Rand, cs []fr.Element, ds []fr.Element) ([]fr.Element, []fr.Element, error) {
 	n := len(cs)
 
 	// Generate all the blinders but leave out two blinders from z
@@ -395,36 +429,83 @@ func (p *Proof) FromReader(r io.Reader) error {
 	d := bls12381.NewDecoder(r)
 
 	if err := d.Decode(&tmp); err != nil {
-		return fmt.Errorf("decode B_c: %s", err)
+		return fmt.Errorf("%w: decode B_c: %s", common.ErrMalformedProof, err)
 	}
 	p.B_c.FromAffine(&tmp)
 
 	if err := d.Decode(&tmp); err != nil {
-		return fmt.Errorf("decode B_d: %s", err)
+		return fmt.Errorf("%w: decode B_d: %s", common.ErrMalformedProof, err)
 	}
 	p.B_d.FromAffine(&tmp)
 
 	if err := common.DecodeAffineSliceToJac(d, &p.L_Cs); err != nil {
-		return fmt.Errorf("decode L_Cs: %s", err)
+		return fmt.Errorf("%w: decode L_Cs: %s", common.ErrMalformedProof, err)
 	}
 	if err := common.DecodeAffineSliceToJac(d, &p.R_Cs); err != nil {
-		return fmt.Errorf("decode R_Cs: %s", err)
+		return fmt.Errorf("%w: decode R_Cs: %s", common.ErrMalformedProof, err)
 	}
 	if err := common.DecodeAffineSliceToJac(d, &p.L_Ds); err != nil {
-		return fmt.Errorf("decode L_Ds: %s", err)
+		return fmt.Errorf("%w: decode L_Ds: %s", common.ErrMalformedProof, err)
 	}
 	if err := common.DecodeAffineSliceToJac(d, &p.R_Ds); err != nil {
-		return fmt.Errorf("decode R_Ds: %s", err)
+		return fmt.Errorf("%w: decode R_Ds: %s", common.ErrMalformedProof, err)
+	}
+	if err := d.Decode(&p.C0); err != nil {
+		return fmt.Errorf("%w: decode c0: %s", common.ErrMalformedProof, err)
+	}
+	if err := d.Decode(&p.D0); err != nil {
+		return fmt.Errorf("%w: decode d0: %s", common.ErrMalformedProof, err)
+	}
+	return nil
+}
+
+// FromReaderStrict is like FromReader but only accepts the canonical encoding of
+// a proof for vectors of size n.
+func (p *Proof) FromReaderStrict(r io.Reader, n int) error {
+	m, err := common.Log2(n)
+	if err != nil {
+		return fmt.Errorf("invalid n: %s", err)
+	}
+	d := common.NewStrictDecoder(r)
+	if err := d.DecodeG1Jac(&p.B_c); err != nil {
+		return fmt.Errorf("%w: decode B_c: %s", common.ErrMalformedProof, err)
+	}
+	if err := d.DecodeG1Jac(&p.B_d); err != nil {
+		return fmt.Errorf("%w: decode B_d: %s", common.ErrMalformedProof, err)
+	}
+	if err := d.DecodeG1JacSlice(&p.L_Cs, m); err != nil {
+		return fmt.Errorf("%w: decode L_Cs: %s", common.ErrMalformedProof, err)
+	}
+	if err := d.DecodeG1JacSlice(&p.R_Cs, m); err != nil {
+		return fmt.Errorf("%w: decode R_Cs: %s", common.ErrMalformedProof, err)
+	}
+	if err := d.DecodeG1JacSlice(&p.L_Ds, m); err != nil {
+		return fmt.Errorf("%w: decode L_Ds: %s", common.ErrMalformedProof, err)
 	}
-	if err := d.Decode(&p.c0); err != nil {
-		return fmt.Errorf("decode c0: %s", err)
+	if err := d.DecodeG1JacSlice(&p.R_Ds, m); err != nil {
+		return fmt.Errorf("%w: decode R_Ds: %s", common.ErrMalformedProof, err)
 	}
-	if err := d.Decode(&p.d0); err != nil {
-		return fmt.Errorf("decode d0: %s", err)
+	if err := d.DecodeFr(&p.C0); err != nil {
+		return fmt.Errorf("%w: decode c0: %s", common.ErrMalformedProof, err)
+	}
+	if err := d.DecodeFr(&p.D0); err != nil {
+		return fmt.Errorf("%w: decode d0: %s", common.ErrMalformedProof, err)
 	}
 	return nil
 }
 
+// ProofSize returns the size of the encoding of a proof for vectors of size n.
+func ProofSize(n int) (int, error) {
+	m, err := common.Log2(n)
+	if err != nil {
+		return 0, fmt.Errorf("invalid n: %s", err)
+	}
+	// B_c, B_d, L_Cs, R_Cs, L_Ds, R_Ds, C0 and D0.
+	return 2*bls12381.SizeOfG1AffineCompressed +
+		4*(common.SliceLenSize+m*bls12381.SizeOfG1AffineCompressed) +
+		2*fr.Bytes, nil
+}
+
 func (p *Proof) Serialize(w io.Writer) error {
 	b_cd := bls12381.BatchJacobianToAffineG1([]bls12381.G1Jac{p.B_c, p.B_d})
 	e := bls12381.NewEncoder(w)
@@ -450,11 +531,70 @@ func (p *Proof) Serialize(w io.Writer) error {
 	if err := e.Encode(affR_Ds); err != nil {
 		return fmt.Errorf("encode R_Ds: %s", err)
 	}
-	if err := e.Encode(&p.c0); err != nil {
+	if err := e.Encode(&p.C0); err != nil {
 		return fmt.Errorf("encode c0: %s", err)
 	}
-	if err := e.Encode(&p.d0); err != nil {
+	if err := e.Encode(&p.D0); err != nil {
 		return fmt.Errorf("encode d0: %s", err)
 	}
 	return nil
 }
+
+type proofJSON struct {
+	B_c  string   `json:"B_c"`
+	B_d  string   `json:"B_d"`
+	L_Cs []string `json:"L_Cs"`
+	R_Cs []string `json:"R_Cs"`
+	L_Ds []string `json:"L_Ds"`
+	R_Ds []string `json:"R_Ds"`
+	C0   string   `json:"c0"`
+	D0   string   `json:"d0"`
+}
+
+// MarshalJSON encodes the proof with 0x-prefixed compressed points and scalars.
+func (p Proof) MarshalJSON() ([]byte, error) {
+	bs := common.G1JacsToHex([]bls12381.G1Jac{p.B_c, p.B_d})
+	return json.Marshal(proofJSON{
+		B_c:  bs[0],
+		B_d:  bs[1],
+		L_Cs: common.G1JacsToHex(p.L_Cs),
+		R_Cs: common.G1JacsToHex(p.R_Cs),
+		L_Ds: common.G1JacsToHex(p.L_Ds),
+		R_Ds: common.G1JacsToHex(p.R_Ds),
+		C0:   common.FrToHex(&p.C0),
+		D0:   common.FrToHex(&p.D0),
+	})
+}
+
+func (p *Proof) UnmarshalJSON(data []byte) error {
+	var pj proofJSON
+	if err := json.Unmarshal(data, &pj); err != nil {
+		return err
+	}
+	var err error
+	if p.B_c, err = common.G1JacFromHex(pj.B_c); err != nil {
+		return fmt.Errorf("%w: decode B_c: %s", common.ErrMalformedProof, err)
+	}
+	if p.B_d, err = common.G1JacFromHex(pj.B_d); err != nil {
+		return fmt.Errorf("%w: decode B_d: %s", common.ErrMalformedProof, err)
+	}
+	if p.L_Cs, err = common.G1JacsFromHex(pj.L_Cs); err != nil {
+		return fmt.Errorf("%w: decode L_Cs: %s", common.ErrMalformedProof, err)
+	}
+	if p.R_Cs, err = common.G1JacsFromHex(pj.R_Cs); err != nil {
+		return fmt.Errorf("%w: decode R_Cs: %s", common.ErrMalformedProof, err)
+	}
+	if p.L_Ds, err = common.G1JacsFromHex(pj.L_Ds); err != nil {
+		return fmt.Errorf("%w: decode L_Ds: %s", common.ErrMalformedProof, err)
+	}
+	if p.R_Ds, err = common.G1JacsFromHex(pj.R_Ds); err != nil {
+		return fmt.Errorf("%w: decode R_Ds: %s", common.ErrMalformedProof, err)
+	}
+	if p.C0, err = common.FrFromHex(pj.C0); err != nil {
+		return fmt.Errorf("%w: decode c0: %s", common.ErrMalformedProof, err)
+	}
+	if p.D0, err = common.FrFromHex(pj.D0); err != nil {
+		return fmt.Errorf("%w: decode d0: %s", common.ErrMalformedProof, err)
+	}
+	return nil
+}
@@ -21,7 +21,7 @@ func TestInnerProductArgument(t *testing.T) {
 		transcript := transcript.New([]byte("IPA"))
 		crs, B, C, z, bs, cs, _ := setup(t, n)
 
-		rand, err := common.NewRand(42)
+		rand, err := common.NewTestingRand(42)
 		require.NoError(t, err)
 		proof, err = Prove(
 			crs,
@@ -32,6 +32,7 @@ func TestInnerProductArgument(t *testing.T) {
 			cs,
 			transcript,
 			rand,
+			common.Exec{},
 		)
 		require.NoError(t, err)
 	}
@@ -41,7 +42,7 @@ func TestInnerProductArgument(t *testing.T) {
 		msmAccumulator := msmaccumulator.New()
 		crs, B, C, z, _, _, us := setup(t, n)
 
-		rand, err := common.NewRand(43)
+		rand, err := common.NewTestingRand(43)
 		require.NoError(t, err)
 
 		ok, err := Verify(
@@ -54,11 +55,12 @@ func TestInnerProductArgument(t *testing.T) {
 			transcript,
 			msmAccumulator,
 			rand,
+			common.Exec{},
 		)
 		require.NoError(t, err)
 		require.True(t, ok)
 
-		ok, err = msmAccumulator.Verify()
+		ok, err = msmAccumulator.Verify(common.Exec{})
 		require.NoError(t, err)
 		require.True(t, ok)
 	})
@@ -79,7 +81,7 @@ func TestInnerProductArgument(t *testing.T) {
 }
 
 func setup(t *testing.T, n int) (CRS, bls12381.G1Jac, bls12381.G1Jac, fr.Element, []fr.Element, []fr.Element, []fr.Element) {
-	rand, err := common.NewRand(0)
+	rand, err := common.NewTestingRand(0)
 	require.NoError(t, err)
 
 	crsGs, err := rand.GetG1Affines(n)
@@ -111,10 +113,10 @@ func setup(t *testing.T, n int) (CRS, bls12381.G1Jac, bls12381.G1Jac, fr.Element
 
 	// Create commitments
 	var B bls12381.G1Jac
-	_, err = B.MultiExp(crs.Gs, bs, common.MultiExpConf)
+	_, err = B.MultiExp(crs.Gs, bs, common.Exec{}.MultiExpConf())
 	require.NoError(t, err)
 	var C bls12381.G1Jac
-	_, err = C.MultiExp(crs.Gs_prime, cs, common.MultiExpConf)
+	_, err = C.MultiExp(crs.Gs_prime, cs, common.Exec{}.MultiExpConf())
 	require.NoError(t, err)
 
 	return crs, B, C, z, bs, cs, us
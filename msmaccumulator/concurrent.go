@@ -0,0 +1,71 @@
+package msmaccumulator
+
+import (
+	"sync"
+
+	bls12381 "github.com/consensys/gnark-crypto/ecc/bls12-381"
+	"github.com/consensys/gnark-crypto/ecc/bls12-381/fr"
+	"github.com/jsign/curdleproofs/common"
+)
+
+// ConcurrentMsmAccumulator is an MsmAccumulator safe for concurrent use. Workers
+// can accumulate checks on it directly, or build their own MsmAccumulator and
+// merge it once done, which only takes the lock once.
+//
+// Keyed points are ordered by the order in which concurrent calls take the
+// lock, so the final MSM input order isn't deterministic, but the verdict is.
+type ConcurrentMsmAccumulator struct {
+	mu sync.Mutex
+	ma *MsmAccumulator
+}
+
+// NewConcurrent returns a concurrent accumulator using table, which can be nil.
+func NewConcurrent(table *Table) *ConcurrentMsmAccumulator {
+	return &ConcurrentMsmAccumulator{ma: NewWithTable(table)}
+}
+
+// AccumulateCheck is like MsmAccumulator.AccumulateCheck. rand is only used
+// while holding the lock.
+func (c *ConcurrentMsmAccumulator) AccumulateCheck(
+	label string,
+	C bls12381.G1Jac,
+	x []fr.Element,
+	basis *Basis,
+	rand common.Rand) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.ma.AccumulateCheck(label, C, x, basis, rand)
+}
+
+// Merge adds the checks accumulated by other, which must not be modified
+// concurrently.
+func (c *ConcurrentMsmAccumulator) Merge(other *MsmAccumulator) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.ma.Merge(other)
+}
+
+// EnableDebug is like MsmAccumulator.EnableDebug.
+func (c *ConcurrentMsmAccumulator) EnableDebug() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.ma.EnableDebug()
+}
+
+func (c *ConcurrentMsmAccumulator) FailingChecks(exec common.Exec) ([]string, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.ma.FailingChecks(exec)
+}
+
+func (c *ConcurrentMsmAccumulator) Reset() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.ma.Reset()
+}
+
+func (c *ConcurrentMsmAccumulator) Verify(exec common.Exec) (bool, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.ma.Verify(exec)
+}
@@ -8,25 +8,128 @@ import (
 	"github.com/jsign/curdleproofs/common"
 )
 
+// Table is a fixed vector of points, such as the CRS bases, shared by the
+// accumulators of many verifications. Its points are referred to by their index
+// in the table, or handle, so they're accumulated without hashing them.
+// A Table is safe for concurrent use.
+type Table struct {
+	points []bls12381.G1Affine
+}
+
+// Basis is a vector of points that checks are accumulated against. The points
+// of a Basis returned by Table.Register are accumulated by handle, and the ones
+// of a Basis returned by NewBasis by value.
+type Basis struct {
+	points  []bls12381.G1Affine
+	table   *Table
+	handles []int
+}
+
+// NewBasis returns a basis whose points are accumulated by value.
+func NewBasis(points []bls12381.G1Affine) *Basis {
+	return &Basis{points: points}
+}
+
+// Points returns the points of the basis.
+func (b *Basis) Points() []bls12381.G1Affine {
+	return b.points
+}
+
+// NewTable returns a table with the given points.
+func NewTable(points []bls12381.G1Affine) *Table {
+	return &Table{points: points}
+}
+
+// Register checks that basis[i] is the table point with handle handles[i], and
+// returns a Basis whose checks are accumulated by handle by the accumulators
+// using t.
+func (t *Table) Register(basis []bls12381.G1Affine, handles []int) (*Basis, error) {
+	if len(basis) != len(handles) {
+		return nil, fmt.Errorf("basis has %d points but got %d handles", len(basis), len(handles))
+	}
+	for i, h := range handles {
+		if h < 0 || h >= len(t.points) {
+			return nil, fmt.Errorf("handle %d is out of range", h)
+		}
+		if !basis[i].Equal(&t.points[h]) {
+			return nil, fmt.Errorf("basis point %d isn't the table point %d", i, h)
+		}
+	}
+	return &Basis{points: basis, table: t, handles: handles}, nil
+}
+
+// MsmAccumulator batches checks of the form C = <x, v> into a single MSM. Points
+// of bases registered in its table are accumulated by handle; any other point
+// is keyed by value, in the order it was first accumulated. The final MSM input
+// order only depends on the sequence of checks, so verification is
+// reproducible.
 type MsmAccumulator struct {
-	A_c           bls12381.G1Jac
-	baseScalarMap map[bls12381.G1Affine]fr.Element
+	A_c bls12381.G1Jac
+
+	table        *Table
+	tableScalars []fr.Element
+
+	points      []bls12381.G1Affine
+	scalars     []fr.Element
+	pointsIndex map[bls12381.G1Affine]int
+
+	debug  bool
+	checks []check
+}
+
+// check is an accumulated check kept in debug mode.
+type check struct {
+	label string
+	C     bls12381.G1Jac
+	x     []fr.Element
+	v     []bls12381.G1Affine
 }
 
+// New returns an accumulator that keys every point by value.
 func New() *MsmAccumulator {
-	return &MsmAccumulator{
-		A_c:           bls12381.G1Jac{},
-		baseScalarMap: make(map[bls12381.G1Affine]fr.Element),
+	return NewWithTable(nil)
+}
+
+// NewWithTable returns an accumulator that accumulates the registered bases of
+// table by handle.
+func NewWithTable(table *Table) *MsmAccumulator {
+	ma := &MsmAccumulator{
+		table:       table,
+		pointsIndex: make(map[bls12381.G1Affine]int),
 	}
+	if table != nil {
+		ma.tableScalars = make([]fr.Element, len(table.points))
+	}
+	return ma
+}
+
+// EnableDebug makes the accumulator keep every check, so each one can be
+// verified on its own with FailingChecks. It must be called before accumulating
+// any check.
+func (ma *MsmAccumulator) EnableDebug() {
+	ma.debug = true
 }
 
+// AccumulateCheck defers the check C = <x, v>, where v are the points of basis.
+// label names the check in FailingChecks. The points are accumulated by handle
+// if basis was registered in the table of the accumulator, and by value
+// otherwise.
 func (ma *MsmAccumulator) AccumulateCheck(
+	label string,
 	C bls12381.G1Jac,
 	x []fr.Element,
-	v []bls12381.G1Affine,
-	rand *common.Rand) error {
+	basis *Basis,
+	rand common.Rand) error {
+	if basis == nil {
+		return fmt.Errorf("%s: nil basis", label)
+	}
+	v := basis.points
 	if len(v) != len(x) {
-		return fmt.Errorf("x and v must have the same length")
+		return fmt.Errorf("%s: x and v must have the same length", label)
+	}
+	if ma.debug {
+		// Callers can reuse x after the call, but not the bases.
+		ma.checks = append(ma.checks, check{label: label, C: C, x: append([]fr.Element(nil), x...), v: v})
 	}
 
 	alpha, err := rand.GetFr()
@@ -35,30 +138,132 @@ func (ma *MsmAccumulator) AccumulateCheck(
 	}
 
 	var tmp fr.Element
-	for i := 0; i < len(v); i++ {
-		tmp.Mul(&alpha, &x[i])
-		scalar := ma.baseScalarMap[v[i]]
-		scalar.Add(&scalar, &tmp)
-		ma.baseScalarMap[v[i]] = scalar
+	if basis.table != nil && basis.table == ma.table {
+		for i, h := range basis.handles {
+			tmp.Mul(&alpha, &x[i])
+			ma.tableScalars[h].Add(&ma.tableScalars[h], &tmp)
+		}
+	} else {
+		for i := 0; i < len(v); i++ {
+			tmp.Mul(&alpha, &x[i])
+			ma.addPoint(&v[i], &tmp)
+		}
 	}
 	ma.A_c.AddAssign(C.ScalarMultiplication(&C, common.FrToBigInt(&alpha)))
 
 	return nil
 }
 
-func (ma *MsmAccumulator) Verify() (bool, error) {
-	x := make([]fr.Element, 0, len(ma.baseScalarMap))
-	v := make([]bls12381.G1Affine, 0, len(ma.baseScalarMap))
+// addPoint adds scalar to the keyed scalar of point.
+func (ma *MsmAccumulator) addPoint(point *bls12381.G1Affine, scalar *fr.Element) {
+	idx, ok := ma.pointsIndex[*point]
+	if !ok {
+		idx = len(ma.points)
+		ma.pointsIndex[*point] = idx
+		ma.points = append(ma.points, *point)
+		ma.scalars = append(ma.scalars, fr.Element{})
+	}
+	ma.scalars[idx].Add(&ma.scalars[idx], scalar)
+}
 
-	for vi := range ma.baseScalarMap {
-		v = append(v, vi)
-		x = append(x, ma.baseScalarMap[vi])
+// Merge adds the checks accumulated by other, so verifying ma afterwards is
+// equivalent to having accumulated every check of both in ma. If other uses a
+// different table, its table points are merged through the keyed path. In debug
+// mode, the checks kept by other are kept too, or if other isn't in debug mode,
+// its aggregate is kept as a single check labeled "merged". other isn't modified.
+func (ma *MsmAccumulator) Merge(other *MsmAccumulator) {
+	if ma.debug {
+		if other.debug {
+			ma.checks = append(ma.checks, other.checks...)
+		} else {
+			v, x := other.msmInput()
+			ma.checks = append(ma.checks, check{
+				label: "merged",
+				C:     other.A_c,
+				// other can be reset and reused after the call.
+				x: append([]fr.Element(nil), x...),
+				v: append([]bls12381.G1Affine(nil), v...),
+			})
+		}
+	}
+	if other.table != nil {
+		if other.table == ma.table {
+			for i := range other.tableScalars {
+				ma.tableScalars[i].Add(&ma.tableScalars[i], &other.tableScalars[i])
+			}
+		} else {
+			for i := range other.tableScalars {
+				if !other.tableScalars[i].IsZero() {
+					ma.addPoint(&other.table.points[i], &other.tableScalars[i])
+				}
+			}
+		}
+	}
+	for i := range other.points {
+		ma.addPoint(&other.points[i], &other.scalars[i])
 	}
+	ma.A_c.AddAssign(&other.A_c)
+}
 
+// Reset discards every accumulated check, keeping the table and the allocated
+// memory.
+func (ma *MsmAccumulator) Reset() {
+	ma.A_c = bls12381.G1Jac{}
+	for i := range ma.tableScalars {
+		ma.tableScalars[i].SetZero()
+	}
+	ma.points = ma.points[:0]
+	ma.scalars = ma.scalars[:0]
+	for p := range ma.pointsIndex {
+		delete(ma.pointsIndex, p)
+	}
+	ma.checks = ma.checks[:0]
+}
+
+// msmInput returns the points and scalars of the aggregated MSM: the table
+// points followed by the other points.
+func (ma *MsmAccumulator) msmInput() ([]bls12381.G1Affine, []fr.Element) {
+	if ma.table == nil {
+		return ma.points, ma.scalars
+	}
+	v := make([]bls12381.G1Affine, 0, len(ma.table.points)+len(ma.points))
+	v = append(v, ma.table.points...)
+	v = append(v, ma.points...)
+	x := make([]fr.Element, 0, len(v))
+	x = append(x, ma.tableScalars...)
+	x = append(x, ma.scalars...)
+	return v, x
+}
+
+func (ma *MsmAccumulator) Verify(exec common.Exec) (bool, error) {
+	v, x := ma.msmInput()
 	var msmRes bls12381.G1Jac
-	if _, err := msmRes.MultiExp(v, x, common.MultiExpConf); err != nil {
+	if _, err := msmRes.MultiExp(v, x, exec.MultiExpConf()); err != nil {
 		return false, fmt.Errorf("computing msm: %s", err)
 	}
 
 	return msmRes.Equal(&ma.A_c), nil
 }
+
+// FailingChecks verifies each check kept in debug mode on its own, with an MSM
+// per check, and returns the labels of the ones that don't hold, in the order
+// they were accumulated.
+func (ma *MsmAccumulator) FailingChecks(exec common.Exec) ([]string, error) {
+	if !ma.debug {
+		return nil, fmt.Errorf("debug mode isn't enabled")
+	}
+	var failing []string
+	for _, c := range ma.checks {
+		if err := exec.Err(); err != nil {
+			return nil, err
+		}
+		var res bls12381.G1Jac
+		if _, err := res.MultiExp(c.v, c.x, exec.MultiExpConf()); err != nil {
+			return nil, fmt.Errorf("%s: computing msm: %s", c.label, err)
+		}
+		if !res.Equal(&c.C) {
+			failing = append(failing, c.label)
+		}
+	}
+	return failing, nil
+}
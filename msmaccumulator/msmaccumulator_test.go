@@ -2,9 +2,11 @@ package msmaccumulator
 
 import (
 	"strconv"
+	"sync"
 	"testing"
 
 	bls12381 "github.com/consensys/gnark-crypto/ecc/bls12-381"
+	"github.com/consensys/gnark-crypto/ecc/bls12-381/fr"
 	"github.com/jsign/curdleproofs/common"
 	"github.com/stretchr/testify/require"
 )
@@ -17,7 +19,7 @@ func TestMSMAccumulator(t *testing.T) {
 			t.Parallel()
 
 			var err error
-			rand, err := common.NewRand(0)
+			rand, err := common.NewTestingRand(0)
 			require.NoError(t, err)
 
 			A, err := rand.GetG1Affines(n)
@@ -25,7 +27,7 @@ func TestMSMAccumulator(t *testing.T) {
 			x, err := rand.GetFrs(n)
 			require.NoError(t, err)
 			var C1 bls12381.G1Jac
-			_, err = C1.MultiExp(A, x, common.MultiExpConf)
+			_, err = C1.MultiExp(A, x, common.Exec{}.MultiExpConf())
 			require.NoError(t, err)
 
 			B, err := rand.GetG1Affines(n)
@@ -33,18 +35,314 @@ func TestMSMAccumulator(t *testing.T) {
 			y, err := rand.GetFrs(n)
 			require.NoError(t, err)
 			var C2 bls12381.G1Jac
-			_, err = C2.MultiExp(B, y, common.MultiExpConf)
+			_, err = C2.MultiExp(B, y, common.Exec{}.MultiExpConf())
 			require.NoError(t, err)
 
 			ma := New()
-			err = ma.AccumulateCheck(C1, x, A, rand)
+			err = ma.AccumulateCheck("C1", C1, x, NewBasis(A), rand)
 			require.NoError(t, err)
-			err = ma.AccumulateCheck(C2, y, B, rand)
+			err = ma.AccumulateCheck("C2", C2, y, NewBasis(B), rand)
 			require.NoError(t, err)
 
-			ok, err := ma.Verify()
+			ok, err := ma.Verify(common.Exec{})
 			require.NoError(t, err)
 			require.True(t, ok)
 		})
 	}
 }
+
+func TestMSMAccumulatorTable(t *testing.T) {
+	t.Parallel()
+
+	rand, err := common.NewTestingRand(0)
+	require.NoError(t, err)
+
+	n := 8
+	points, err := rand.GetG1Affines(2 * n)
+	require.NoError(t, err)
+	table := NewTable(points)
+
+	// A basis registered with handles in reverse order, and the same points
+	// without registering them, so they go through the keyed path.
+	reversed := make([]bls12381.G1Affine, n)
+	handles := make([]int, n)
+	for i := range reversed {
+		handles[i] = 2*n - 1 - i
+		reversed[i] = points[handles[i]]
+	}
+	registered, err := table.Register(reversed, handles)
+	require.NoError(t, err)
+	unregistered := NewBasis(reversed)
+
+	_, err = table.Register(reversed, handles[1:])
+	require.Error(t, err)
+	_, err = table.Register(reversed[1:], handles[:n-1])
+	require.Error(t, err)
+	_, err = table.Register(reversed[:1], []int{2 * n})
+	require.Error(t, err)
+	_, err = table.Register(reversed[:1], handles[1:2])
+	require.Error(t, err)
+	empty, err := table.Register(nil, nil)
+	require.NoError(t, err)
+
+	x, err := rand.GetFrs(n)
+	require.NoError(t, err)
+	var C bls12381.G1Jac
+	_, err = C.MultiExp(reversed, x, common.Exec{}.MultiExpConf())
+	require.NoError(t, err)
+
+	verify := func(seed uint64, C bls12381.G1Jac) (*MsmAccumulator, bool) {
+		rand, err := common.NewTestingRand(seed)
+		require.NoError(t, err)
+		ma := NewWithTable(table)
+		require.NoError(t, ma.AccumulateCheck("registered", C, x, registered, rand))
+		require.NoError(t, ma.AccumulateCheck("unregistered", C, x, unregistered, rand))
+		require.NoError(t, ma.AccumulateCheck("empty", bls12381.G1Jac{}, nil, empty, rand))
+		ok, err := ma.Verify(common.Exec{})
+		require.NoError(t, err)
+		return ma, ok
+	}
+
+	ma, ok := verify(1, C)
+	require.True(t, ok)
+	require.Len(t, ma.points, n)
+	require.Equal(t, reversed, ma.points)
+
+	// A basis registered in another table is accumulated by value.
+	otherTable := NewWithTable(NewTable(points))
+	rand, err = common.NewTestingRand(1)
+	require.NoError(t, err)
+	require.NoError(t, otherTable.AccumulateCheck("registered", C, x, registered, rand))
+	require.Equal(t, reversed, otherTable.points)
+	ok, err = otherTable.Verify(common.Exec{})
+	require.NoError(t, err)
+	require.True(t, ok)
+
+	require.Error(t, ma.AccumulateCheck("nil", C, x, nil, rand))
+
+	// The accumulated state only depends on the checks and the randomness.
+	again, ok := verify(1, C)
+	require.True(t, ok)
+	require.Equal(t, ma.tableScalars, again.tableScalars)
+	require.Equal(t, ma.scalars, again.scalars)
+	require.True(t, ma.A_c.Equal(&again.A_c))
+
+	var wrongC bls12381.G1Jac
+	wrongC.Double(&C)
+	_, ok = verify(1, wrongC)
+	require.False(t, ok)
+}
+
+func TestMSMAccumulatorMerge(t *testing.T) {
+	t.Parallel()
+
+	rand, err := common.NewTestingRand(0)
+	require.NoError(t, err)
+
+	n := 8
+	points, err := rand.GetG1Affines(n)
+	require.NoError(t, err)
+	table := NewTable(points)
+	basis, err := table.Register(points, []int{0, 1, 2, 3, 4, 5, 6, 7})
+	require.NoError(t, err)
+
+	// Checks alternate between the table basis and ad-hoc points, some of them
+	// equal to table points.
+	type check struct {
+		C bls12381.G1Jac
+		x []fr.Element
+		v *Basis
+	}
+	checks := make([]check, 6)
+	for i := range checks {
+		v := basis
+		if i%2 == 1 {
+			points, err := rand.GetG1Affines(n)
+			require.NoError(t, err)
+			points[0] = basis.Points()[0]
+			v = NewBasis(points)
+		}
+		x, err := rand.GetFrs(n)
+		require.NoError(t, err)
+		var C bls12381.G1Jac
+		_, err = C.MultiExp(v.Points(), x, common.Exec{}.MultiExpConf())
+		require.NoError(t, err)
+		checks[i] = check{C, x, v}
+	}
+	invalidChecks := append([]check(nil), checks...)
+	invalidChecks[3].C.Double(&invalidChecks[3].C)
+
+	accumulate := func(ma *MsmAccumulator, checks []check, seed uint64) {
+		rand, err := common.NewTestingRand(seed)
+		require.NoError(t, err)
+		for _, c := range checks {
+			require.NoError(t, ma.AccumulateCheck("check", c.C, c.x, c.v, rand))
+		}
+	}
+	verify := func(ma interface {
+		Verify(common.Exec) (bool, error)
+	}) bool {
+		ok, err := ma.Verify(common.Exec{})
+		require.NoError(t, err)
+		return ok
+	}
+
+	for _, tc := range []struct {
+		checks   []check
+		expected bool
+	}{{checks, true}, {invalidChecks, false}} {
+		checks, expected := tc.checks, tc.expected
+		sequential := NewWithTable(table)
+		accumulate(sequential, checks, 1)
+		require.Equal(t, expected, verify(sequential))
+
+		// Partial accumulators with the same table, another table and no table.
+		merged := NewWithTable(table)
+		accumulate(merged, checks[:2], 2)
+		sameTable, otherTable, noTable := NewWithTable(table), NewWithTable(NewTable(points)), New()
+		accumulate(sameTable, checks[2:3], 3)
+		accumulate(otherTable, checks[3:4], 4)
+		accumulate(noTable, checks[4:], 5)
+		merged.Merge(sameTable)
+		merged.Merge(otherTable)
+		merged.Merge(noTable)
+		require.Equal(t, expected, verify(merged))
+
+		noTable.Merge(sequential)
+		require.Equal(t, expected, verify(noTable))
+
+		concurrent := NewConcurrent(table)
+		errs := make([]error, len(checks))
+		var wg sync.WaitGroup
+		for i := range checks {
+			wg.Add(1)
+			go func(i int) {
+				defer wg.Done()
+				rand, err := common.NewTestingRand(uint64(10 + i))
+				if err != nil {
+					errs[i] = err
+					return
+				}
+				c := checks[i]
+				if i%2 == 0 {
+					errs[i] = concurrent.AccumulateCheck("check", c.C, c.x, c.v, rand)
+					return
+				}
+				partial := NewWithTable(table)
+				if errs[i] = partial.AccumulateCheck("check", c.C, c.x, c.v, rand); errs[i] == nil {
+					concurrent.Merge(partial)
+				}
+			}(i)
+		}
+		wg.Wait()
+		for _, err := range errs {
+			require.NoError(t, err)
+		}
+		require.Equal(t, expected, verify(concurrent))
+
+		concurrent.Reset()
+		require.True(t, verify(concurrent))
+		merged.Reset()
+		require.True(t, verify(merged))
+		accumulate(merged, checks[:3], 6)
+		require.True(t, verify(merged))
+	}
+}
+
+func TestMSMAccumulatorFailingChecks(t *testing.T) {
+	t.Parallel()
+
+	rand, err := common.NewTestingRand(0)
+	require.NoError(t, err)
+
+	n := 4
+	labels := []string{"first", "second", "third"}
+	checks := make([]struct {
+		C bls12381.G1Jac
+		x []fr.Element
+		v []bls12381.G1Affine
+	}, len(labels))
+	for i := range checks {
+		checks[i].v, err = rand.GetG1Affines(n)
+		require.NoError(t, err)
+		checks[i].x, err = rand.GetFrs(n)
+		require.NoError(t, err)
+		_, err = checks[i].C.MultiExp(checks[i].v, checks[i].x, common.Exec{}.MultiExpConf())
+		require.NoError(t, err)
+	}
+	checks[1].C.Double(&checks[1].C)
+
+	ma := New()
+	_, err = ma.FailingChecks(common.Exec{})
+	require.Error(t, err)
+
+	ma.EnableDebug()
+	for i, c := range checks {
+		require.NoError(t, ma.AccumulateCheck(labels[i], c.C, c.x, NewBasis(c.v), rand))
+		// Reusing x after accumulating doesn't change the kept check.
+		c.x[0].SetZero()
+	}
+	ok, err := ma.Verify(common.Exec{})
+	require.NoError(t, err)
+	require.False(t, ok)
+
+	failing, err := ma.FailingChecks(common.Exec{})
+	require.NoError(t, err)
+	require.Equal(t, []string{"second"}, failing)
+
+	merged := New()
+	merged.EnableDebug()
+	merged.Merge(ma)
+	failing, err = merged.FailingChecks(common.Exec{})
+	require.NoError(t, err)
+	require.Equal(t, []string{"second"}, failing)
+
+	ma.Reset()
+	failing, err = ma.FailingChecks(common.Exec{})
+	require.NoError(t, err)
+	require.Empty(t, failing)
+}
+
+func TestMSMAccumulatorMergeMixedDebug(t *testing.T) {
+	t.Parallel()
+
+	rand, err := common.NewTestingRand(0)
+	require.NoError(t, err)
+
+	accumulate := func(valid bool) *MsmAccumulator {
+		v, err := rand.GetG1Affines(4)
+		require.NoError(t, err)
+		x, err := rand.GetFrs(4)
+		require.NoError(t, err)
+		var C bls12381.G1Jac
+		_, err = C.MultiExp(v, x, common.Exec{}.MultiExpConf())
+		require.NoError(t, err)
+		if !valid {
+			C.Double(&C)
+		}
+		ma := New()
+		require.NoError(t, ma.AccumulateCheck("check", C, x, NewBasis(v), rand))
+		return ma
+	}
+
+	// Merging accumulators without debug mode into one with it keeps each
+	// aggregate as a check.
+	merged := New()
+	merged.EnableDebug()
+	merged.Merge(accumulate(true))
+	failing, err := merged.FailingChecks(common.Exec{})
+	require.NoError(t, err)
+	require.Empty(t, failing)
+
+	invalid := accumulate(false)
+	merged.Merge(invalid)
+	// Reusing the merged accumulator doesn't change the kept check.
+	invalid.Reset()
+	invalid.Merge(accumulate(true))
+	failing, err = merged.FailingChecks(common.Exec{})
+	require.NoError(t, err)
+	require.Equal(t, []string{"merged"}, failing)
+	ok, err := merged.Verify(common.Exec{})
+	require.NoError(t, err)
+	require.False(t, ok)
+}
@@ -0,0 +1,109 @@
+package curdleproof
+
+import (
+	"context"
+	"encoding/binary"
+	"fmt"
+	"sync"
+
+	"github.com/jsign/curdleproofs/common"
+	"github.com/jsign/curdleproofs/transcript"
+)
+
+// headerVersion is the version of the domain separation header absorbed by
+// WithDomainSeparation.
+const headerVersion = 1
+
+// Option configures Prove and Verify.
+type Option func(*config)
+
+type config struct {
+	domainSeparation bool
+	context          []byte
+	exec             common.Exec
+
+	// failedChecks is set by Diagnose to collect the failing checks that
+	// aren't deferred to the MSM accumulator, instead of stopping at the
+	// first one.
+	failedChecks *[]string
+}
+
+// WithDomainSeparation absorbs a versioned header into the transcript before the
+// instance. The header binds the CRS digest, the shuffle size, the number of
+// blinders and an application-supplied context, so a proof only verifies under
+// the same CRS, parameters and context. Without it the transcript matches the
+// reference implementation.
+func WithDomainSeparation(context []byte) Option {
+	return func(c *config) {
+		c.domainSeparation = true
+		c.context = context
+	}
+}
+
+// WithNbTasks limits the number of goroutines used by the MSMs and folds. By
+// default, every CPU is used.
+func WithNbTasks(nbTasks int) Option {
+	return func(c *config) {
+		c.exec.NbTasks = nbTasks
+	}
+}
+
+// WithContext aborts proving or verifying once ctx is done, returning its error.
+// The context is checked between protocol steps and folding rounds.
+func WithContext(ctx context.Context) Option {
+	return func(c *config) {
+		c.exec.Ctx = ctx
+	}
+}
+
+// NewExec returns the execution settings selected by opts, for helpers such as
+// common.ShufflePermuteCommit that run outside Prove and Verify.
+func NewExec(opts ...Option) common.Exec {
+	return newConfig(opts).exec
+}
+
+func newConfig(opts []Option) config {
+	var c config
+	for _, opt := range opts {
+		opt(&c)
+	}
+	return c
+}
+
+// appendHeader appends version || crs digest || ell || nBlinders || len(context) || context
+// to the transcript, with integers encoded as big-endian uint64s. The digest is
+// only computed if the header is enabled.
+func appendHeader(transcript *transcript.Transcript, digest *crsDigest, ell int, nBlinders int, c config) error {
+	if !c.domainSeparation {
+		return nil
+	}
+	crsDigest, err := digest.get()
+	if err != nil {
+		return fmt.Errorf("computing crs digest: %s", err)
+	}
+	header := make([]byte, 0, 1+len(crsDigest)+8+8+8+len(c.context))
+	header = append(header, headerVersion)
+	header = append(header, crsDigest[:]...)
+	header = binary.BigEndian.AppendUint64(header, uint64(ell))
+	header = binary.BigEndian.AppendUint64(header, uint64(nBlinders))
+	header = binary.BigEndian.AppendUint64(header, uint64(len(c.context)))
+	header = append(header, c.context...)
+	transcript.AppendMessage(labelHeader, header)
+	return nil
+}
+
+// crsDigest computes the digest of a CRS the first time it's needed, since only
+// the domain separation header uses it.
+type crsDigest struct {
+	crs    *CRS
+	once   sync.Once
+	digest [32]byte
+	err    error
+}
+
+func (d *crsDigest) get() ([32]byte, error) {
+	d.once.Do(func() {
+		d.digest, d.err = d.crs.Digest()
+	})
+	return d.digest, d.err
+}
@@ -0,0 +1,292 @@
+package curdleproof
+
+import (
+	"fmt"
+
+	bls12381 "github.com/consensys/gnark-crypto/ecc/bls12-381"
+	"github.com/consensys/gnark-crypto/ecc/bls12-381/fr"
+	"github.com/jsign/curdleproofs/common"
+	"github.com/jsign/curdleproofs/groupcommitment"
+	"github.com/jsign/curdleproofs/samemultiscalarargument"
+	"github.com/jsign/curdleproofs/samepermutationargument"
+	"github.com/jsign/curdleproofs/samescalarargument"
+	"github.com/jsign/curdleproofs/transcript"
+)
+
+// Prover proves shuffles against a fixed CRS. It computes the CRS-derived bases
+// and fixed-base tables once, so it's cheaper than Prove when proving many
+// shuffles. A Prover is safe for concurrent use.
+type Prover struct {
+	// crs is the CRS given to NewProver padded to the size of the arguments,
+	// and size the number of Gs bases it had. MPad is added to M, see CRS.padded.
+	crs    CRS
+	size   int
+	MPad   bls12381.G1Jac
+	digest *crsDigest
+
+	// G is Gs || Hs[:len(Hs)-2] || Gt || Gu, the basis of the same multiscalar argument.
+	G []bls12381.G1Affine
+	// GsHs is Gs || Hs, the basis of the grand product argument.
+	GsHs    []bls12381.G1Affine
+	HAffine bls12381.G1Affine
+
+	tableH  *common.FixedBaseTable
+	tableGt *common.FixedBaseTable
+	tableGu *common.FixedBaseTable
+}
+
+// NewProver validates crs and precomputes the data used by Prove.
+func NewProver(crs CRS) (*Prover, error) {
+	if err := crs.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid crs: %w", err)
+	}
+	p, err := newProver(crs)
+	if err != nil {
+		return nil, err
+	}
+	p.tableH = common.NewFixedBaseTable(&crs.H)
+	p.tableGt = common.NewFixedBaseTable(&crs.Gt)
+	p.tableGu = common.NewFixedBaseTable(&crs.Gu)
+
+	return p, nil
+}
+
+// newProver returns a Prover without fixed-base tables, which only pay off after
+// a few proofs. It only checks the shape of crs, see Prove.
+func newProver(crs CRS) (*Prover, error) {
+	if err := crs.checkShape(); err != nil {
+		return nil, fmt.Errorf("invalid crs: %w", err)
+	}
+	// The digest is of the CRS as given, which also binds the padding bases.
+	original := crs
+	digest := &crsDigest{crs: &original}
+	crs, MPad, err := original.padded(digest)
+	if err != nil {
+		return nil, fmt.Errorf("padding crs: %s", err)
+	}
+
+	G := make([]bls12381.G1Affine, 0, len(crs.Gs)+(len(crs.Hs)-2)+1+1)
+	G = append(G, crs.Gs...)
+	G = append(G, crs.Hs[:len(crs.Hs)-2]...)
+	gxaffine := bls12381.BatchJacobianToAffineG1([]bls12381.G1Jac{crs.Gt, crs.Gu})
+	G = append(G, gxaffine...)
+
+	GsHs := make([]bls12381.G1Affine, 0, len(crs.Gs)+len(crs.Hs))
+	GsHs = append(GsHs, crs.Gs...)
+	GsHs = append(GsHs, crs.Hs...)
+
+	var HAffine bls12381.G1Affine
+	HAffine.FromJacobian(&crs.H)
+
+	return &Prover{
+		crs:     crs,
+		size:    len(original.Gs),
+		MPad:    MPad,
+		digest:  digest,
+		G:       G,
+		GsHs:    GsHs,
+		HAffine: HAffine,
+	}, nil
+}
+
+func (p *Prover) Prove(
+	Rs []bls12381.G1Affine,
+	Ss []bls12381.G1Affine,
+	Ts []bls12381.G1Affine,
+	Us []bls12381.G1Affine,
+	M bls12381.G1Jac,
+	perm []uint32,
+	k fr.Element,
+	rs_m []fr.Element,
+	rand common.Rand,
+	opts ...Option,
+) (Proof, error) {
+	if err := validateInstance(Instance{Rs: Rs, Ss: Ss, Ts: Ts, Us: Us, M: M}, p.size); err != nil {
+		return Proof{}, err
+	}
+	if len(perm) != len(Rs) {
+		return Proof{}, fmt.Errorf("%w: permutation has %d elements but instance has %d", ErrLengthMismatch, len(perm), len(Rs))
+	}
+	c := newConfig(opts)
+	exec := c.exec
+	transcript := transcript.New(labelTranscript)
+	if err := appendHeader(transcript, p.digest, len(Rs), len(p.crs.Hs), c); err != nil {
+		return Proof{}, err
+	}
+
+	// The proof is for the instance padded to the CRS size, see Instance.pad.
+	padded := Instance{Rs: Rs, Ss: Ss, Ts: Ts, Us: Us, M: M}.pad(len(p.crs.Gs))
+	Rs, Ss, Ts, Us = padded.Rs, padded.Ss, padded.Ts, padded.Us
+	M.AddAssign(&p.MPad)
+	perm = common.PadPermutation(perm, len(p.crs.Gs))
+
+	// Step 1
+	transcript.AppendPointsAffine(labelStep1, Rs...)
+	transcript.AppendPointsAffine(labelStep1, Ss...)
+	transcript.AppendPointsAffine(labelStep1, Ts...)
+	transcript.AppendPointsAffine(labelStep1, Us...)
+	transcript.AppendPoints(labelStep1, M)
+	as := transcript.GetAndAppendChallenges(labelVecA, len(Rs))
+
+	// Step 2
+	if err := exec.Err(); err != nil {
+		return Proof{}, err
+	}
+	rs_a, err := rand.GetFrs(len(p.crs.Hs) - 2)
+	if err != nil {
+		return Proof{}, fmt.Errorf("getting rs_a: %s", err)
+	}
+
+	rs_a_prime := make([]fr.Element, 0, len(rs_a)+1+1)
+	rs_a_prime = append(rs_a_prime, rs_a...)
+	rs_a_prime = append(rs_a_prime, zeroFr, zeroFr)
+
+	perm_as := common.Permute(as, perm)
+
+	var A, A_L, A_R bls12381.G1Jac
+	if _, err := A_L.MultiExp(p.crs.Gs, perm_as, exec.MultiExpConf()); err != nil {
+		return Proof{}, fmt.Errorf("computing A_L: %s", err)
+	}
+	if _, err := A_R.MultiExp(p.crs.Hs, rs_a_prime, exec.MultiExpConf()); err != nil {
+		return Proof{}, fmt.Errorf("computing A_R: %s", err)
+	}
+	A.Set(&A_L).AddAssign(&A_R)
+
+	proofSamePerm, err := samepermutationargument.Prove(
+		samepermutationargument.CRS{
+			Gs:   p.crs.Gs,
+			Hs:   p.crs.Hs,
+			H:    p.crs.H,
+			GsHs: p.GsHs,
+		},
+		A,
+		M,
+		as,
+		perm,
+		rs_a_prime,
+		rs_m,
+		transcript,
+		rand,
+		exec,
+	)
+	if err != nil {
+		return Proof{}, fmt.Errorf("proving same permutation: %w", err)
+	}
+
+	// Step 3
+	if err := exec.Err(); err != nil {
+		return Proof{}, err
+	}
+	r_t, err := rand.GetFr()
+	if err != nil {
+		return Proof{}, fmt.Errorf("getting random r_t: %s", err)
+	}
+	r_u, err := rand.GetFr()
+	if err != nil {
+		return Proof{}, fmt.Errorf("getting random r_u: %s", err)
+	}
+	var R bls12381.G1Jac
+	if _, err := R.MultiExp(Rs, as, exec.MultiExpConf()); err != nil {
+		return Proof{}, fmt.Errorf("computing R: %s", err)
+	}
+	var S bls12381.G1Jac
+	if _, err := S.MultiExp(Ss, as, exec.MultiExpConf()); err != nil {
+		return Proof{}, fmt.Errorf("computing S: %s", err)
+	}
+
+	var tmp bls12381.G1Jac
+	tmp.ScalarMultiplication(&R, common.FrToBigInt(&k))
+	T := p.commit(&p.crs.Gt, p.tableGt, tmp, r_t)
+	tmp.ScalarMultiplication(&S, common.FrToBigInt(&k))
+	U := p.commit(&p.crs.Gu, p.tableGu, tmp, r_u)
+
+	// TODO(jsign): enforce assumption in callees about mutation of parameters.
+	ProofSameScalar, err := samescalarargument.Prove(
+		samescalarargument.CRS{
+			Gt: p.crs.Gt,
+			Gu: p.crs.Gu,
+			H:  p.crs.H,
+		},
+		R,
+		S,
+		T,
+		U,
+		k,
+		r_t,
+		r_u,
+		transcript,
+		rand,
+	)
+	if err != nil {
+		return Proof{}, fmt.Errorf("proving same scalar: %s", err)
+	}
+
+	// Step 4
+	if err := exec.Err(); err != nil {
+		return Proof{}, err
+	}
+	A_prime := A
+	A_prime.AddAssign(&T.T_1)
+	A_prime.AddAssign(&U.T_1)
+
+	TsSuffix, UsSuffix := multiscalarSuffixes(len(p.crs.Hs), p.HAffine)
+
+	T_prime := make([]bls12381.G1Affine, 0, len(Ts)+len(TsSuffix))
+	T_prime = append(T_prime, Ts...)
+	T_prime = append(T_prime, TsSuffix...)
+
+	U_prime := make([]bls12381.G1Affine, 0, len(Us)+len(UsSuffix))
+	U_prime = append(U_prime, Us...)
+	U_prime = append(U_prime, UsSuffix...)
+
+	x := make([]fr.Element, 0, len(perm_as)+len(rs_a)+1+1)
+	x = append(x, perm_as...)
+	x = append(x, rs_a...)
+	x = append(x, r_t, r_u)
+
+	ProofSameMultiscalar, err := samemultiscalarargument.Prove(
+		p.G,
+		A_prime,
+		T.T_2,
+		U.T_2,
+		T_prime,
+		U_prime,
+		x,
+		transcript,
+		rand,
+		exec,
+	)
+	if err != nil {
+		return Proof{}, fmt.Errorf("proving same multiscalar: %w", err)
+	}
+
+	return Proof{
+		A,
+		T,
+		U,
+		R,
+		S,
+		proofSamePerm,
+		ProofSameScalar,
+		ProofSameMultiscalar,
+	}, nil
+}
+
+// commit returns the group commitment (r*G, T + r*H), using the fixed-base
+// tables if the prover has them.
+func (p *Prover) commit(
+	G *bls12381.G1Jac,
+	tableG *common.FixedBaseTable,
+	T bls12381.G1Jac,
+	r fr.Element,
+) groupcommitment.GroupCommitment {
+	if tableG == nil {
+		return groupcommitment.New(*G, p.crs.H, T, r)
+	}
+	rH := p.tableH.Mul(&r)
+	T.AddAssign(&rH)
+	return groupcommitment.GroupCommitment{
+		T_1: tableG.Mul(&r),
+		T_2: T,
+	}
+}
@@ -0,0 +1,133 @@
+package curdleproof
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"sync"
+	"testing"
+
+	bls12381 "github.com/consensys/gnark-crypto/ecc/bls12-381"
+	"github.com/jsign/curdleproofs/common"
+	"github.com/stretchr/testify/require"
+)
+
+func TestProver(t *testing.T) {
+	t.Parallel()
+
+	// SHA-256 of the serialized proofs generated by setup(n) and a testing rand
+	// seeded with 42, before the Prover was introduced.
+	expectedDigests := map[int]string{
+		64:  "65e86dff8dfffe1845df290c0a5feb20a1bc9b80615d3a02ffcbddee37b7c7bb",
+		128: "f44a27ed8175e9d162e4c47becdaa81895cd56fb1b94c535398ca9ed49b57876",
+	}
+	for n, expectedDigest := range expectedDigests {
+		n, expectedDigest := n, expectedDigest
+		t.Run(fmt.Sprintf("n=%d", n), func(t *testing.T) {
+			t.Parallel()
+
+			crs, Rs, Ss, Ts, Us, M, perm, k, rs_m := setup(t, n)
+
+			rand, err := common.NewTestingRand(42)
+			require.NoError(t, err)
+			proof, err := Prove(crs, Rs, Ss, Ts, Us, M, perm, k, rs_m, rand)
+			require.NoError(t, err)
+			require.Equal(t, expectedDigest, proofDigest(t, proof))
+
+			prover, err := NewProver(crs)
+			require.NoError(t, err)
+			G := append([]bls12381.G1Affine(nil), prover.G...)
+			GsHs := append([]bls12381.G1Affine(nil), prover.GsHs...)
+			for i := 0; i < 2; i++ {
+				rand, err := common.NewTestingRand(42)
+				require.NoError(t, err)
+				proof, err := prover.Prove(Rs, Ss, Ts, Us, M, perm, k, rs_m, rand)
+				require.NoError(t, err)
+				require.Equal(t, expectedDigest, proofDigest(t, proof))
+
+				// Proving must not mutate the precomputed bases.
+				require.Equal(t, G, prover.G)
+				require.Equal(t, GsHs, prover.GsHs)
+			}
+		})
+	}
+}
+
+func TestProverConcurrent(t *testing.T) {
+	t.Parallel()
+
+	n := 64
+	crs, Rs, Ss, Ts, Us, M, perm, k, rs_m := setup(t, n)
+	prover, err := NewProver(crs)
+	require.NoError(t, err)
+
+	var wg sync.WaitGroup
+	proofs := make([]Proof, 4)
+	errs := make([]error, len(proofs))
+	for i := range proofs {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			proofs[i], errs[i] = prover.Prove(Rs, Ss, Ts, Us, M, perm, k, rs_m, common.NewSecureRand())
+		}(i)
+	}
+	wg.Wait()
+
+	for i := range proofs {
+		require.NoError(t, errs[i])
+		ok, err := Verify(proofs[i], crs, Rs, Ss, Ts, Us, M, common.NewSecureRand())
+		require.NoError(t, err)
+		require.True(t, ok)
+	}
+}
+
+func BenchmarkProverReuse(b *testing.B) {
+	for _, n := range []int{128, 512} {
+		crs, Rs, Ss, Ts, Us, M, perm, k, rs_m := setup(b, n)
+		rand, err := common.NewTestingRand(42)
+		require.NoError(b, err)
+
+		b.Run(fmt.Sprintf("Prove/shuffled elements=%d", n-common.N_BLINDERS), func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				_, _ = Prove(crs, Rs, Ss, Ts, Us, M, perm, k, rs_m, rand)
+			}
+		})
+		b.Run(fmt.Sprintf("Prover.Prove/shuffled elements=%d", n-common.N_BLINDERS), func(b *testing.B) {
+			prover, err := NewProver(crs)
+			require.NoError(b, err)
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				_, _ = prover.Prove(Rs, Ss, Ts, Us, M, perm, k, rs_m, rand)
+			}
+		})
+	}
+}
+
+func proofDigest(t *testing.T, proof Proof) string {
+	buf := bytes.NewBuffer(nil)
+	require.NoError(t, proof.Serialize(buf))
+	digest := sha256.Sum256(buf.Bytes())
+	return hex.EncodeToString(digest[:])
+}
+
+// BenchmarkProverParallelism compares proving on a single task with the default,
+// which splits the MSMs and folds across all CPUs.
+func BenchmarkProverParallelism(b *testing.B) {
+	for _, n := range []int{128, 512} {
+		crs, Rs, Ss, Ts, Us, M, perm, k, rs_m := setup(b, n)
+		rand, err := common.NewTestingRand(42)
+		require.NoError(b, err)
+
+		b.Run(fmt.Sprintf("nbTasks=1/shuffled elements=%d", n-common.N_BLINDERS), func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				_, _ = Prove(crs, Rs, Ss, Ts, Us, M, perm, k, rs_m, rand, WithNbTasks(1))
+			}
+		})
+		b.Run(fmt.Sprintf("default/shuffled elements=%d", n-common.N_BLINDERS), func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				_, _ = Prove(crs, Rs, Ss, Ts, Us, M, perm, k, rs_m, rand)
+			}
+		})
+	}
+}
@@ -1,10 +1,12 @@
 package samemultiscalarargument
 
 import (
+	"encoding/json"
 	"fmt"
 	"io"
 	"math/bits"
 
+	"github.com/consensys/gnark-crypto/ecc"
 	bls12381 "github.com/consensys/gnark-crypto/ecc/bls12-381"
 	"github.com/consensys/gnark-crypto/ecc/bls12-381/fr"
 	"github.com/jsign/curdleproofs/common"
@@ -31,7 +33,7 @@ type Proof struct {
 	R_T []bls12381.G1Jac
 	R_U []bls12381.G1Jac
 
-	x fr.Element
+	X fr.Element
 }
 
 func Prove(
@@ -43,7 +45,8 @@ func Prove(
 	U []bls12381.G1Affine,
 	x []fr.Element,
 	transcript *transcript.Transcript,
-	rand *common.Rand,
+	rand common.Rand,
+	exec common.Exec,
 ) (Proof, error) {
 	n := uint(len(x))
 	m := bits.Len(n) - 1
@@ -61,14 +64,27 @@ func Prove(
 	}
 
 	var B_a, B_t, B_u bls12381.G1Jac
-	if _, err := B_a.MultiExp(G, r, common.MultiExpConf); err != nil {
-		return Proof{}, fmt.Errorf("computing B_a: %s", err)
-	}
-	if _, err := B_t.MultiExp(T, r, common.MultiExpConf); err != nil {
-		return Proof{}, fmt.Errorf("computing B_t: %s", err)
-	}
-	if _, err := B_u.MultiExp(U, r, common.MultiExpConf); err != nil {
-		return Proof{}, fmt.Errorf("computing B_u: %s", err)
+	if err := exec.Parallel(
+		func(conf ecc.MultiExpConfig) error {
+			if _, err := B_a.MultiExp(G, r, conf); err != nil {
+				return fmt.Errorf("computing B_a: %s", err)
+			}
+			return nil
+		},
+		func(conf ecc.MultiExpConfig) error {
+			if _, err := B_t.MultiExp(T, r, conf); err != nil {
+				return fmt.Errorf("computing B_t: %s", err)
+			}
+			return nil
+		},
+		func(conf ecc.MultiExpConfig) error {
+			if _, err := B_u.MultiExp(U, r, conf); err != nil {
+				return fmt.Errorf("computing B_u: %s", err)
+			}
+			return nil
+		},
+	); err != nil {
+		return Proof{}, err
 	}
 
 	transcript.AppendPoints(labelStep1, A, Z_t, Z_u)
@@ -82,7 +98,13 @@ func Prove(
 		x[i].Add(&r[i], tmp.Mul(&x[i], &alpha))
 	}
 
+	// The basis is folded into a fresh buffer, so G isn't mutated.
+	G_folded := make([]bls12381.G1Affine, n/2)
+
 	for len(x) > 1 {
+		if err := exec.Err(); err != nil {
+			return Proof{}, err
+		}
 		n /= 2
 
 		x_L, x_R := common.SplitAt(x, n)
@@ -90,24 +112,33 @@ func Prove(
 		U_L, U_R := common.SplitAt(U, n)
 		G_L, G_R := common.SplitAt(G, n)
 
+		// The six MSMs are independent, so they run concurrently.
 		var L_A, L_T, L_U, R_A, R_T, R_U bls12381.G1Jac
-		if _, err := L_A.MultiExp(G_R, x_L, common.MultiExpConf); err != nil {
-			return Proof{}, fmt.Errorf("computing L_A: %s", err)
-		}
-		if _, err := L_T.MultiExp(T_R, x_L, common.MultiExpConf); err != nil {
-			return Proof{}, fmt.Errorf("computing L_T: %s", err)
-		}
-		if _, err := L_U.MultiExp(U_R, x_L, common.MultiExpConf); err != nil {
-			return Proof{}, fmt.Errorf("computing L_U: %s", err)
+		msms := []struct {
+			name   string
+			out    *bls12381.G1Jac
+			points []bls12381.G1Affine
+			x      []fr.Element
+		}{
+			{"L_A", &L_A, G_R, x_L},
+			{"L_T", &L_T, T_R, x_L},
+			{"L_U", &L_U, U_R, x_L},
+			{"R_A", &R_A, G_L, x_R},
+			{"R_T", &R_T, T_L, x_R},
+			{"R_U", &R_U, U_L, x_R},
 		}
-		if _, err := R_A.MultiExp(G_L, x_R, common.MultiExpConf); err != nil {
-			return Proof{}, fmt.Errorf("computing R_A: %s", err)
-		}
-		if _, err := R_T.MultiExp(T_L, x_R, common.MultiExpConf); err != nil {
-			return Proof{}, fmt.Errorf("computing R_T: %s", err)
+		fns := make([]func(conf ecc.MultiExpConfig) error, len(msms))
+		for i := range msms {
+			msm := msms[i]
+			fns[i] = func(conf ecc.MultiExpConfig) error {
+				if _, err := msm.out.MultiExp(msm.points, msm.x, conf); err != nil {
+					return fmt.Errorf("computing %s: %s", msm.name, err)
+				}
+				return nil
+			}
 		}
-		if _, err := R_U.MultiExp(U_L, x_R, common.MultiExpConf); err != nil {
-			return Proof{}, fmt.Errorf("computing R_U: %s", err)
+		if err := exec.Parallel(fns...); err != nil {
+			return Proof{}, err
 		}
 
 		L_As = append(L_As, L_A)
@@ -127,16 +158,19 @@ func Prove(
 
 		// Fold vectors and basis
 		for i := 0; i < int(n); i++ {
-			gammaBigInt := common.FrToBigInt(&gamma)
 			x_L[i].Add(&x_L[i], (&fr.Element{}).Mul(&gamma_inv, &x_R[i]))
-			T_L[i].Add(&T_L[i], (&bls12381.G1Affine{}).ScalarMultiplication(&T_R[i], gammaBigInt))
-			U_L[i].Add(&U_L[i], (&bls12381.G1Affine{}).ScalarMultiplication(&U_R[i], gammaBigInt))
-			G_L[i].Add(&G_L[i], (&bls12381.G1Affine{}).ScalarMultiplication(&G_R[i], gammaBigInt))
 		}
+		gammaBigInt := common.FrToBigInt(&gamma)
+		common.FoldG1(
+			exec,
+			common.G1Fold{Out: T_L, L: T_L, R: T_R, Scalar: gammaBigInt},
+			common.G1Fold{Out: U_L, L: U_L, R: U_R, Scalar: gammaBigInt},
+			common.G1Fold{Out: G_folded[:n], L: G_L, R: G_R, Scalar: gammaBigInt},
+		)
 		x = x_L
 		T = T_L
 		U = U_L
-		G = G_L
+		G = G_folded[:n]
 	}
 	if len(x) != 1 {
 		return Proof{}, fmt.Errorf("unexpected length of x")
@@ -152,13 +186,15 @@ func Prove(
 		R_A: R_As,
 		R_T: R_Ts,
 		R_U: R_Us,
-		x:   x[0],
+		X:   x[0],
 	}, nil
 }
 
+// Verify verifies the proof against the basis G, which can be registered in the
+// table of msmacc.
 func Verify(
 	proof Proof,
-	G []bls12381.G1Affine,
+	G *msmaccumulator.Basis,
 	A bls12381.G1Jac,
 	Z_t bls12381.G1Jac,
 	Z_u bls12381.G1Jac,
@@ -166,7 +202,8 @@ func Verify(
 	U []bls12381.G1Affine,
 	transcript *transcript.Transcript,
 	msmacc *msmaccumulator.MsmAccumulator,
-	rand *common.Rand,
+	rand common.Rand,
+	exec common.Exec,
 ) (bool, error) {
 	n := len(T)
 
@@ -178,12 +215,12 @@ func Verify(
 
 	gamma, gamma_inv, s, err := unfoldedScalars(&proof, n, transcript)
 	if err != nil {
-		return false, fmt.Errorf("computing verification scalars: %s", err)
+		return false, fmt.Errorf("computing verification scalars: %w", err)
 	}
 
 	xtimess := make([]fr.Element, len(s))
 	for i := 0; i < len(s); i++ {
-		xtimess[i].Mul(&proof.x, &s[i])
+		xtimess[i].Mul(&proof.X, &s[i])
 	}
 
 	var A_a, Z_t_a, Z_u_a bls12381.G1Jac
@@ -193,42 +230,42 @@ func Verify(
 
 	var l, p, r bls12381.G1Jac
 	L_A_Affine := bls12381.BatchJacobianToAffineG1(proof.L_A)
-	if _, err := l.MultiExp(L_A_Affine, gamma, common.MultiExpConf); err != nil {
+	if _, err := l.MultiExp(L_A_Affine, gamma, exec.MultiExpConf()); err != nil {
 		return false, fmt.Errorf("computing point_lhs msm: %s", err)
 	}
 	R_A_Affine := bls12381.BatchJacobianToAffineG1(proof.R_A)
-	if _, err := r.MultiExp(R_A_Affine, gamma_inv, common.MultiExpConf); err != nil {
+	if _, err := r.MultiExp(R_A_Affine, gamma_inv, exec.MultiExpConf()); err != nil {
 		return false, fmt.Errorf("computing point_lhs msm: %s", err)
 	}
 	p.Set(&A_a).AddAssign(&l).AddAssign(&r)
 	var p_affine bls12381.G1Affine
 	p_affine.FromJacobian(&p)
-	if err := msmacc.AccumulateCheck(p, xtimess, G, rand); err != nil {
+	if err := msmacc.AccumulateCheck("same multiscalar A", p, xtimess, G, rand); err != nil {
 		return false, fmt.Errorf("accumulating msm 1: %s", err)
 	}
 	L_T_Affine := bls12381.BatchJacobianToAffineG1(proof.L_T)
-	if _, err := l.MultiExp(L_T_Affine, gamma, common.MultiExpConf); err != nil {
+	if _, err := l.MultiExp(L_T_Affine, gamma, exec.MultiExpConf()); err != nil {
 		return false, fmt.Errorf("computing point_lhs msm: %s", err)
 	}
 	R_T_Affine := bls12381.BatchJacobianToAffineG1(proof.R_T)
-	if _, err := r.MultiExp(R_T_Affine, gamma_inv, common.MultiExpConf); err != nil {
+	if _, err := r.MultiExp(R_T_Affine, gamma_inv, exec.MultiExpConf()); err != nil {
 		return false, fmt.Errorf("computing point_lhs msm: %s", err)
 	}
 	p.Set(&Z_t_a).AddAssign(&l).AddAssign(&r)
-	if err := msmacc.AccumulateCheck(p, xtimess, T, rand); err != nil {
+	if err := msmacc.AccumulateCheck("same multiscalar T", p, xtimess, msmaccumulator.NewBasis(T), rand); err != nil {
 		return false, fmt.Errorf("accumulating msm 1: %s", err)
 	}
 
 	L_U_Affine := bls12381.BatchJacobianToAffineG1(proof.L_U)
-	if _, err := l.MultiExp(L_U_Affine, gamma, common.MultiExpConf); err != nil {
+	if _, err := l.MultiExp(L_U_Affine, gamma, exec.MultiExpConf()); err != nil {
 		return false, fmt.Errorf("computing point_lhs msm: %s", err)
 	}
 	R_U_Affine := bls12381.BatchJacobianToAffineG1(proof.R_U)
-	if _, err := r.MultiExp(R_U_Affine, gamma_inv, common.MultiExpConf); err != nil {
+	if _, err := r.MultiExp(R_U_Affine, gamma_inv, exec.MultiExpConf()); err != nil {
 		return false, fmt.Errorf("computing point_lhs msm: %s", err)
 	}
 	p.Set(&Z_u_a).AddAssign(&l).AddAssign(&r)
-	if err := msmacc.AccumulateCheck(p, xtimess, U, rand); err != nil {
+	if err := msmacc.AccumulateCheck("same multiscalar U", p, xtimess, msmaccumulator.NewBasis(U), rand); err != nil {
 		return false, fmt.Errorf("accumulating msm 1: %s", err)
 	}
 	return true, nil
@@ -243,11 +280,14 @@ func unfoldedScalars(
 ) ([]fr.Element, []fr.Element, []fr.Element, error) {
 	lg_n := len(proof.L_A)
 	if lg_n >= maxRecursiveSteps {
-		return nil, nil, nil, fmt.Errorf("recursive steps greater than expected")
+		return nil, nil, nil, fmt.Errorf("%w: recursive steps greater than expected", common.ErrMalformedProof)
 	}
 
 	if n != (1 << lg_n) {
-		return nil, nil, nil, fmt.Errorf("must by log2(L_a)")
+		return nil, nil, nil, fmt.Errorf("%w: must by log2(L_a)", common.ErrMalformedProof)
+	}
+	if len(proof.L_T) != lg_n || len(proof.L_U) != lg_n || len(proof.R_A) != lg_n || len(proof.R_T) != lg_n || len(proof.R_U) != lg_n {
+		return nil, nil, nil, fmt.Errorf("%w: proof vectors must have length %d", common.ErrMalformedProof, lg_n)
 	}
 
 	challenges := make([]fr.Element, 0, lg_n)
@@ -264,19 +304,7 @@ func unfoldedScalars(
 		challenges = append(challenges, transcript.GetAndAppendChallenge(labelGamma))
 	}
 
-	ss := make([]fr.Element, 0, n)
-	for i := 0; i < n; i++ {
-		tmp := fr.One()
-		for k := lg_n - 1; k >= 0; k-- {
-			bit := i&(1<<(lg_n-k-1)) > 0
-			if bit {
-				tmp.Mul(&tmp, &challenges[k])
-			}
-		}
-		ss = append(ss, tmp)
-	}
-
-	return challenges, fr.BatchInvert(challenges), ss, nil
+	return challenges, fr.BatchInvert(challenges), common.FoldingScalars(challenges), nil
 }
 
 func (p *Proof) FromReader(r io.Reader) error {
@@ -284,44 +312,87 @@ func (p *Proof) FromReader(r io.Reader) error {
 	var tmp bls12381.G1Affine
 
 	if err := d.Decode(&tmp); err != nil {
-		return fmt.Errorf("decoding B_a: %s", err)
+		return fmt.Errorf("%w: decoding B_a: %s", common.ErrMalformedProof, err)
 	}
 	p.B_a.FromAffine(&tmp)
 
 	if err := d.Decode(&tmp); err != nil {
-		return fmt.Errorf("decoding B_t: %s", err)
+		return fmt.Errorf("%w: decoding B_t: %s", common.ErrMalformedProof, err)
 	}
 	p.B_t.FromAffine(&tmp)
 
 	if err := d.Decode(&tmp); err != nil {
-		return fmt.Errorf("decoding B_u: %s", err)
+		return fmt.Errorf("%w: decoding B_u: %s", common.ErrMalformedProof, err)
 	}
 	p.B_u.FromAffine(&tmp)
 
 	if err := common.DecodeAffineSliceToJac(d, &p.L_A); err != nil {
-		return fmt.Errorf("decoding L_A: %s", err)
+		return fmt.Errorf("%w: decoding L_A: %s", common.ErrMalformedProof, err)
 	}
 	if err := common.DecodeAffineSliceToJac(d, &p.L_T); err != nil {
-		return fmt.Errorf("decoding L_T: %s", err)
+		return fmt.Errorf("%w: decoding L_T: %s", common.ErrMalformedProof, err)
 	}
 	if err := common.DecodeAffineSliceToJac(d, &p.L_U); err != nil {
-		return fmt.Errorf("decoding L_U: %s", err)
+		return fmt.Errorf("%w: decoding L_U: %s", common.ErrMalformedProof, err)
 	}
 	if err := common.DecodeAffineSliceToJac(d, &p.R_A); err != nil {
-		return fmt.Errorf("decoding R_A: %s", err)
+		return fmt.Errorf("%w: decoding R_A: %s", common.ErrMalformedProof, err)
 	}
 	if err := common.DecodeAffineSliceToJac(d, &p.R_T); err != nil {
-		return fmt.Errorf("decoding R_T: %s", err)
+		return fmt.Errorf("%w: decoding R_T: %s", common.ErrMalformedProof, err)
 	}
 	if err := common.DecodeAffineSliceToJac(d, &p.R_U); err != nil {
-		return fmt.Errorf("decoding R_U: %s", err)
+		return fmt.Errorf("%w: decoding R_U: %s", common.ErrMalformedProof, err)
 	}
-	if err := d.Decode(&p.x); err != nil {
-		return fmt.Errorf("decoding x: %s", err)
+	if err := d.Decode(&p.X); err != nil {
+		return fmt.Errorf("%w: decoding x: %s", common.ErrMalformedProof, err)
 	}
 	return nil
 }
 
+// FromReaderStrict is like FromReader but only accepts the canonical encoding of
+// a proof for vectors of size n.
+func (p *Proof) FromReaderStrict(r io.Reader, n int) error {
+	lg_n, err := common.Log2(n)
+	if err != nil {
+		return fmt.Errorf("invalid n: %s", err)
+	}
+	d := common.NewStrictDecoder(r)
+	if err := d.DecodeG1Jac(&p.B_a); err != nil {
+		return fmt.Errorf("%w: decoding B_a: %s", common.ErrMalformedProof, err)
+	}
+	if err := d.DecodeG1Jac(&p.B_t); err != nil {
+		return fmt.Errorf("%w: decoding B_t: %s", common.ErrMalformedProof, err)
+	}
+	if err := d.DecodeG1Jac(&p.B_u); err != nil {
+		return fmt.Errorf("%w: decoding B_u: %s", common.ErrMalformedProof, err)
+	}
+	for _, v := range []struct {
+		name string
+		out  *[]bls12381.G1Jac
+	}{{"L_A", &p.L_A}, {"L_T", &p.L_T}, {"L_U", &p.L_U}, {"R_A", &p.R_A}, {"R_T", &p.R_T}, {"R_U", &p.R_U}} {
+		if err := d.DecodeG1JacSlice(v.out, lg_n); err != nil {
+			return fmt.Errorf("%w: decoding %s: %s", common.ErrMalformedProof, v.name, err)
+		}
+	}
+	if err := d.DecodeFr(&p.X); err != nil {
+		return fmt.Errorf("%w: decoding x: %s", common.ErrMalformedProof, err)
+	}
+	return nil
+}
+
+// ProofSize returns the size of the encoding of a proof for vectors of size n.
+func ProofSize(n int) (int, error) {
+	lg_n, err := common.Log2(n)
+	if err != nil {
+		return 0, fmt.Errorf("invalid n: %s", err)
+	}
+	// B_a, B_t, B_u, the six L_* and R_* vectors, and x.
+	return 3*bls12381.SizeOfG1AffineCompressed +
+		6*(common.SliceLenSize+lg_n*bls12381.SizeOfG1AffineCompressed) +
+		fr.Bytes, nil
+}
+
 func (p *Proof) Serialize(w io.Writer) error {
 	aff_bs := bls12381.BatchJacobianToAffineG1([]bls12381.G1Jac{p.B_a, p.B_t, p.B_u})
 	e := bls12381.NewEncoder(w)
@@ -358,8 +429,68 @@ func (p *Proof) Serialize(w io.Writer) error {
 	if err := e.Encode(affR_U); err != nil {
 		return fmt.Errorf("encoding R_U: %s", err)
 	}
-	if err := e.Encode(&p.x); err != nil {
+	if err := e.Encode(&p.X); err != nil {
 		return fmt.Errorf("encoding x: %s", err)
 	}
 	return nil
 }
+
+type proofJSON struct {
+	B_a string   `json:"B_a"`
+	B_t string   `json:"B_t"`
+	B_u string   `json:"B_u"`
+	L_A []string `json:"L_A"`
+	L_T []string `json:"L_T"`
+	L_U []string `json:"L_U"`
+	R_A []string `json:"R_A"`
+	R_T []string `json:"R_T"`
+	R_U []string `json:"R_U"`
+	X   string   `json:"x"`
+}
+
+// MarshalJSON encodes the proof with 0x-prefixed compressed points and scalars.
+func (p Proof) MarshalJSON() ([]byte, error) {
+	bs := common.G1JacsToHex([]bls12381.G1Jac{p.B_a, p.B_t, p.B_u})
+	return json.Marshal(proofJSON{
+		B_a: bs[0],
+		B_t: bs[1],
+		B_u: bs[2],
+		L_A: common.G1JacsToHex(p.L_A),
+		L_T: common.G1JacsToHex(p.L_T),
+		L_U: common.G1JacsToHex(p.L_U),
+		R_A: common.G1JacsToHex(p.R_A),
+		R_T: common.G1JacsToHex(p.R_T),
+		R_U: common.G1JacsToHex(p.R_U),
+		X:   common.FrToHex(&p.X),
+	})
+}
+
+func (p *Proof) UnmarshalJSON(data []byte) error {
+	var pj proofJSON
+	if err := json.Unmarshal(data, &pj); err != nil {
+		return err
+	}
+	var err error
+	for _, v := range []struct {
+		name string
+		hex  string
+		out  *bls12381.G1Jac
+	}{{"B_a", pj.B_a, &p.B_a}, {"B_t", pj.B_t, &p.B_t}, {"B_u", pj.B_u, &p.B_u}} {
+		if *v.out, err = common.G1JacFromHex(v.hex); err != nil {
+			return fmt.Errorf("%w: decoding %s: %s", common.ErrMalformedProof, v.name, err)
+		}
+	}
+	for _, v := range []struct {
+		name string
+		hex  []string
+		out  *[]bls12381.G1Jac
+	}{{"L_A", pj.L_A, &p.L_A}, {"L_T", pj.L_T, &p.L_T}, {"L_U", pj.L_U, &p.L_U}, {"R_A", pj.R_A, &p.R_A}, {"R_T", pj.R_T, &p.R_T}, {"R_U", pj.R_U, &p.R_U}} {
+		if *v.out, err = common.G1JacsFromHex(v.hex); err != nil {
+			return fmt.Errorf("%w: decoding %s: %s", common.ErrMalformedProof, v.name, err)
+		}
+	}
+	if p.X, err = common.FrFromHex(pj.X); err != nil {
+		return fmt.Errorf("%w: decoding x: %s", common.ErrMalformedProof, err)
+	}
+	return nil
+}
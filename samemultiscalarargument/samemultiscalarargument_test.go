@@ -17,7 +17,7 @@ func TestSameMultiscalarArgument(t *testing.T) {
 
 	n := 128
 	transcriptProver := transcript.New([]byte("same_msm"))
-	rand, err := common.NewRand(42)
+	rand, err := common.NewTestingRand(42)
 	require.NoError(t, err)
 
 	crs_Gs, A, Z_t, Z_u, Ts, Us, xs := setup(t, n)
@@ -32,12 +32,13 @@ func TestSameMultiscalarArgument(t *testing.T) {
 		xs,
 		transcriptProver,
 		rand,
+		common.Exec{},
 	)
 	require.NoError(t, err)
 
 	t.Run("completeness", func(t *testing.T) {
 		transcriptVerifier := transcript.New([]byte("same_msm"))
-		rand, err := common.NewRand(43)
+		rand, err := common.NewTestingRand(43)
 		require.NoError(t, err)
 
 		crs_Gs, A, Z_t, Z_u, Ts, Us, _ := setup(t, n)
@@ -45,7 +46,7 @@ func TestSameMultiscalarArgument(t *testing.T) {
 
 		ok, err := Verify(
 			proof,
-			crs_Gs,
+			msmaccumulator.NewBasis(crs_Gs),
 			A,
 			Z_t,
 			Z_u,
@@ -54,11 +55,12 @@ func TestSameMultiscalarArgument(t *testing.T) {
 			transcriptVerifier,
 			msmAccumulator,
 			rand,
+			common.Exec{},
 		)
 		require.NoError(t, err)
 		require.True(t, ok)
 
-		ok, err = msmAccumulator.Verify()
+		ok, err = msmAccumulator.Verify(common.Exec{})
 		require.NoError(t, err)
 		require.True(t, ok)
 	})
@@ -80,7 +82,7 @@ func TestSameMultiscalarArgument(t *testing.T) {
 }
 
 func setup(t *testing.T, n int) ([]bls12381.G1Affine, bls12381.G1Jac, bls12381.G1Jac, bls12381.G1Jac, []bls12381.G1Affine, []bls12381.G1Affine, []fr.Element) {
-	rand, err := common.NewRand(0)
+	rand, err := common.NewTestingRand(0)
 	require.NoError(t, err)
 
 	crs_Gs, err := rand.GetG1Affines(n)
@@ -93,11 +95,11 @@ func setup(t *testing.T, n int) ([]bls12381.G1Affine, bls12381.G1Jac, bls12381.G
 	require.NoError(t, err)
 
 	var A, Z_t, Z_u bls12381.G1Jac
-	_, err = A.MultiExp(crs_Gs, xs, common.MultiExpConf)
+	_, err = A.MultiExp(crs_Gs, xs, common.Exec{}.MultiExpConf())
 	require.NoError(t, err)
-	_, err = Z_t.MultiExp(Ts, xs, common.MultiExpConf)
+	_, err = Z_t.MultiExp(Ts, xs, common.Exec{}.MultiExpConf())
 	require.NoError(t, err)
-	_, err = Z_u.MultiExp(Us, xs, common.MultiExpConf)
+	_, err = Z_u.MultiExp(Us, xs, common.Exec{}.MultiExpConf())
 	require.NoError(t, err)
 
 	return crs_Gs, A, Z_t, Z_u, Ts, Us, xs
@@ -1,6 +1,7 @@
 package samepermutationargument
 
 import (
+	"encoding/json"
 	"fmt"
 	"io"
 
@@ -18,15 +19,11 @@ var (
 	labelBeta  = []byte("same_perm_beta")
 )
 
-type CRS struct {
-	Gs []bls12381.G1Affine
-	Hs []bls12381.G1Affine
-	H  bls12381.G1Jac
-}
+type CRS = grandproductargument.CRS
 
 type Proof struct {
 	B        bls12381.G1Jac
-	gpaProof grandproductargument.Proof
+	GPAProof grandproductargument.Proof
 }
 
 func Prove(
@@ -38,7 +35,8 @@ func Prove(
 	rs_a []fr.Element,
 	rs_m []fr.Element,
 	transcript *transcript.Transcript,
-	rand *common.Rand,
+	rand common.Rand,
+	exec common.Exec,
 ) (Proof, error) {
 	// Step 1
 	transcript.AppendPoints(labelStep1, A, M)
@@ -64,7 +62,7 @@ func Prove(
 		betas[i] = beta
 	}
 	var msmBetasGs bls12381.G1Jac
-	if _, err := msmBetasGs.MultiExp(crs.Gs, betas, common.MultiExpConf); err != nil {
+	if _, err := msmBetasGs.MultiExp(crs.Gs, betas, exec.MultiExpConf()); err != nil {
 		return Proof{}, fmt.Errorf("failed to compute msm(Bs, Gs): %s", err)
 	}
 	var alphaM bls12381.G1Jac
@@ -78,25 +76,22 @@ func Prove(
 	}
 
 	gpaproof, err := grandproductargument.Prove(
-		grandproductargument.CRS{
-			Gs: crs.Gs,
-			Hs: crs.Hs,
-			H:  crs.H,
-		},
+		crs,
 		B,
 		p,
 		bs,
 		rs_b,
 		transcript,
 		rand,
+		exec,
 	)
 	if err != nil {
-		return Proof{}, fmt.Errorf("failed to prove grand product argument: %s", err)
+		return Proof{}, fmt.Errorf("failed to prove grand product argument: %w", err)
 	}
 
 	return Proof{
 		B:        B,
-		gpaProof: gpaproof,
+		GPAProof: gpaproof,
 	}, nil
 }
 
@@ -111,8 +106,8 @@ func Verify(
 	numBlinders int,
 	transcript *transcript.Transcript,
 	msmAccumulator *msmaccumulator.MsmAccumulator,
-
-	rand *common.Rand,
+	rand common.Rand,
+	exec common.Exec,
 ) (bool, error) {
 	// Step 1
 	// TODO(jsign): double check FS since doesn't seem to match paper.
@@ -137,17 +132,17 @@ func Verify(
 	var alphaM bls12381.G1Jac
 	alphaM.ScalarMultiplication(&M, common.FrToBigInt(&alpha))
 	C.Set(&proof.B).SubAssign(&A).SubAssign(&alphaM)
-	if err := msmAccumulator.AccumulateCheck(C, betas, crs.Gs, rand); err != nil {
+	Gs := crs.GsBasis
+	if Gs == nil {
+		Gs = msmaccumulator.NewBasis(crs.Gs)
+	}
+	if err := msmAccumulator.AccumulateCheck("same permutation B", C, betas, Gs, rand); err != nil {
 		return false, fmt.Errorf("failed to accumulate check: %s", err)
 	}
 
 	ok, err := grandproductargument.Verify(
-		proof.gpaProof,
-		grandproductargument.CRS{
-			Gs: crs.Gs,
-			Hs: crs.Hs,
-			H:  crs.H,
-		},
+		proof.GPAProof,
+		crs,
 		Gsum,
 		Hsum,
 		proof.B,
@@ -156,9 +151,10 @@ func Verify(
 		transcript,
 		msmAccumulator,
 		rand,
+		exec,
 	)
 	if err != nil {
-		return false, fmt.Errorf("failed to verify grand product argument: %s", err)
+		return false, fmt.Errorf("failed to verify grand product argument: %w", err)
 	}
 	return ok, nil
 }
@@ -168,16 +164,39 @@ func (p *Proof) FromReader(r io.Reader) error {
 	d := bls12381.NewDecoder(r)
 
 	if err := d.Decode(&tmp); err != nil {
-		return fmt.Errorf("failed to decode B: %s", err)
+		return fmt.Errorf("%w: failed to decode B: %s", common.ErrMalformedProof, err)
 	}
 	p.B.FromAffine(&tmp)
 
-	if err := p.gpaProof.FromReader(r); err != nil {
-		return fmt.Errorf("failed to decode GPA proof: %s", err)
+	if err := p.GPAProof.FromReader(r); err != nil {
+		return fmt.Errorf("failed to decode GPA proof: %w", err)
 	}
 	return nil
 }
 
+// FromReaderStrict is like FromReader but only accepts the canonical encoding of
+// a proof for vectors of size n.
+func (p *Proof) FromReaderStrict(r io.Reader, n int) error {
+	d := common.NewStrictDecoder(r)
+	if err := d.DecodeG1Jac(&p.B); err != nil {
+		return fmt.Errorf("%w: failed to decode B: %s", common.ErrMalformedProof, err)
+	}
+	if err := p.GPAProof.FromReaderStrict(r, n); err != nil {
+		return fmt.Errorf("failed to decode GPA proof: %w", err)
+	}
+	return nil
+}
+
+// ProofSize returns the size of the encoding of a proof for vectors of size n.
+func ProofSize(n int) (int, error) {
+	gpaSize, err := grandproductargument.ProofSize(n)
+	if err != nil {
+		return 0, err
+	}
+	// B and GPAProof.
+	return bls12381.SizeOfG1AffineCompressed + gpaSize, nil
+}
+
 func (p *Proof) Serialize(w io.Writer) error {
 	e := bls12381.NewEncoder(w)
 	var bAffine bls12381.G1Affine
@@ -185,8 +204,34 @@ func (p *Proof) Serialize(w io.Writer) error {
 	if err := e.Encode(&bAffine); err != nil {
 		return fmt.Errorf("failed to encode B: %s", err)
 	}
-	if err := p.gpaProof.Serialize(w); err != nil {
+	if err := p.GPAProof.Serialize(w); err != nil {
 		return fmt.Errorf("failed to encode GPA proof: %s", err)
 	}
 	return nil
 }
+
+type proofJSON struct {
+	B        string                     `json:"B"`
+	GPAProof grandproductargument.Proof `json:"grand_product_proof"`
+}
+
+// MarshalJSON encodes the proof with 0x-prefixed compressed points and scalars.
+func (p Proof) MarshalJSON() ([]byte, error) {
+	return json.Marshal(proofJSON{
+		B:        common.G1JacToHex(&p.B),
+		GPAProof: p.GPAProof,
+	})
+}
+
+func (p *Proof) UnmarshalJSON(data []byte) error {
+	var pj proofJSON
+	if err := json.Unmarshal(data, &pj); err != nil {
+		return err
+	}
+	var err error
+	if p.B, err = common.G1JacFromHex(pj.B); err != nil {
+		return fmt.Errorf("%w: failed to decode B: %s", common.ErrMalformedProof, err)
+	}
+	p.GPAProof = pj.GPAProof
+	return nil
+}
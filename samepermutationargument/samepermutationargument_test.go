@@ -19,7 +19,7 @@ func TestSamePermutationArgument(t *testing.T) {
 
 	n := 128
 
-	rand, err := common.NewRand(0)
+	rand, err := common.NewTestingRand(0)
 	require.NoError(t, err)
 
 	crs, A, M, as, perm, rs_a, rs_m := setup(t, n)
@@ -34,6 +34,7 @@ func TestSamePermutationArgument(t *testing.T) {
 		rs_m,
 		transcriptProver,
 		rand,
+		common.Exec{},
 	)
 	require.NoError(t, err)
 
@@ -63,11 +64,12 @@ func TestSamePermutationArgument(t *testing.T) {
 			transcriptVerifier,
 			msmAccumulator,
 			rand,
+			common.Exec{},
 		)
 		require.NoError(t, err)
 		require.True(t, ok)
 
-		ok, err = msmAccumulator.Verify()
+		ok, err = msmAccumulator.Verify(common.Exec{})
 		require.NoError(t, err)
 		require.True(t, ok)
 	})
@@ -88,7 +90,7 @@ func TestSamePermutationArgument(t *testing.T) {
 }
 
 func setup(t *testing.T, n int) (CRS, bls12381.G1Jac, bls12381.G1Jac, []fr.Element, []uint32, []fr.Element, []fr.Element) {
-	rand, err := common.NewRand(0)
+	rand, err := common.NewTestingRand(0)
 	require.NoError(t, err)
 
 	crsGs, err := rand.GetG1Affines(n - common.N_BLINDERS)
@@ -124,16 +126,16 @@ func setup(t *testing.T, n int) (CRS, bls12381.G1Jac, bls12381.G1Jac, []fr.Eleme
 	permAs := common.Permute(as, perm)
 
 	var A, A_L, A_R bls12381.G1Jac
-	_, err = A_L.MultiExp(crsGs, permAs, common.MultiExpConf)
+	_, err = A_L.MultiExp(crsGs, permAs, common.Exec{}.MultiExpConf())
 	require.NoError(t, err)
-	_, err = A_R.MultiExp(crsHs, rs_a, common.MultiExpConf)
+	_, err = A_R.MultiExp(crsHs, rs_a, common.Exec{}.MultiExpConf())
 	require.NoError(t, err)
 	A.Set(&A_L).AddAssign(&A_R)
 
 	var M, M_L, M_R bls12381.G1Jac
-	_, err = M_L.MultiExp(crsGs, permFrs, common.MultiExpConf)
+	_, err = M_L.MultiExp(crsGs, permFrs, common.Exec{}.MultiExpConf())
 	require.NoError(t, err)
-	_, err = M_R.MultiExp(crsHs, rs_m, common.MultiExpConf)
+	_, err = M_R.MultiExp(crsHs, rs_m, common.Exec{}.MultiExpConf())
 	require.NoError(t, err)
 	M.Set(&M_L).AddAssign(&M_R)
 
@@ -1,6 +1,7 @@
 package samescalarargument
 
 import (
+	"encoding/json"
 	"fmt"
 	"io"
 	"math/big"
@@ -41,7 +42,7 @@ func Prove(
 	r_t fr.Element,
 	r_u fr.Element,
 	transcript *transcript.Transcript,
-	rand *common.Rand,
+	rand common.Rand,
 ) (Proof, error) {
 	r_a, err := rand.GetFr()
 	if err != nil {
@@ -101,24 +102,48 @@ func Verify(
 
 func (p *Proof) FromReader(r io.Reader) error {
 	if err := p.A.FromReader(r); err != nil {
-		return fmt.Errorf("read A: %s", err)
+		return fmt.Errorf("read A: %w", err)
 	}
 	if err := p.B.FromReader(r); err != nil {
-		return fmt.Errorf("read B: %s", err)
+		return fmt.Errorf("read B: %w", err)
 	}
 	d := bls12381.NewDecoder(r)
 	if err := d.Decode(&p.Z_k); err != nil {
-		return fmt.Errorf("read Z_k: %s", err)
+		return fmt.Errorf("%w: read Z_k: %s", common.ErrMalformedProof, err)
 	}
 	if err := d.Decode(&p.Z_t); err != nil {
-		return fmt.Errorf("read Z_t: %s", err)
+		return fmt.Errorf("%w: read Z_t: %s", common.ErrMalformedProof, err)
 	}
 	if err := d.Decode(&p.Z_u); err != nil {
-		return fmt.Errorf("read Z_u: %s", err)
+		return fmt.Errorf("%w: read Z_u: %s", common.ErrMalformedProof, err)
 	}
 	return nil
 }
 
+// FromReaderStrict is like FromReader but only accepts the canonical encoding.
+func (p *Proof) FromReaderStrict(r io.Reader) error {
+	if err := p.A.FromReaderStrict(r); err != nil {
+		return fmt.Errorf("read A: %w", err)
+	}
+	if err := p.B.FromReaderStrict(r); err != nil {
+		return fmt.Errorf("read B: %w", err)
+	}
+	d := common.NewStrictDecoder(r)
+	if err := d.DecodeFr(&p.Z_k); err != nil {
+		return fmt.Errorf("%w: read Z_k: %s", common.ErrMalformedProof, err)
+	}
+	if err := d.DecodeFr(&p.Z_t); err != nil {
+		return fmt.Errorf("%w: read Z_t: %s", common.ErrMalformedProof, err)
+	}
+	if err := d.DecodeFr(&p.Z_u); err != nil {
+		return fmt.Errorf("%w: read Z_u: %s", common.ErrMalformedProof, err)
+	}
+	return nil
+}
+
+// ProofSize is the size of the encoding of a proof: A, B, Z_k, Z_t and Z_u.
+const ProofSize = 2*groupcommitment.Size + 3*fr.Bytes
+
 func (p *Proof) Serialize(w io.Writer) error {
 	if err := p.A.Serialize(w); err != nil {
 		return fmt.Errorf("write A: %s", err)
@@ -138,3 +163,41 @@ func (p *Proof) Serialize(w io.Writer) error {
 	}
 	return nil
 }
+
+type proofJSON struct {
+	A   groupcommitment.GroupCommitment `json:"cm_A"`
+	B   groupcommitment.GroupCommitment `json:"cm_B"`
+	Z_k string                          `json:"z_k"`
+	Z_t string                          `json:"z_t"`
+	Z_u string                          `json:"z_u"`
+}
+
+// MarshalJSON encodes the proof with 0x-prefixed compressed points and scalars.
+func (p Proof) MarshalJSON() ([]byte, error) {
+	return json.Marshal(proofJSON{
+		A:   p.A,
+		B:   p.B,
+		Z_k: common.FrToHex(&p.Z_k),
+		Z_t: common.FrToHex(&p.Z_t),
+		Z_u: common.FrToHex(&p.Z_u),
+	})
+}
+
+func (p *Proof) UnmarshalJSON(data []byte) error {
+	var pj proofJSON
+	if err := json.Unmarshal(data, &pj); err != nil {
+		return err
+	}
+	p.A, p.B = pj.A, pj.B
+	var err error
+	if p.Z_k, err = common.FrFromHex(pj.Z_k); err != nil {
+		return fmt.Errorf("%w: read Z_k: %s", common.ErrMalformedProof, err)
+	}
+	if p.Z_t, err = common.FrFromHex(pj.Z_t); err != nil {
+		return fmt.Errorf("%w: read Z_t: %s", common.ErrMalformedProof, err)
+	}
+	if p.Z_u, err = common.FrFromHex(pj.Z_u); err != nil {
+		return fmt.Errorf("%w: read Z_u: %s", common.ErrMalformedProof, err)
+	}
+	return nil
+}
@@ -14,7 +14,7 @@ import (
 func TestProveVerify(t *testing.T) {
 	t.Parallel()
 
-	rand, err := common.NewRand(0)
+	rand, err := common.NewTestingRand(0)
 	require.NoError(t, err)
 
 	transcriptProver := transcript.New([]byte("same_scalar"))
@@ -18,7 +18,7 @@ func New(label []byte) *Transcript {
 	}
 }
 
-func (t *Transcript) appendMessage(label []byte, message []byte) {
+func (t *Transcript) AppendMessage(label []byte, message []byte) {
 	t.inner.AppendMessage(label, message)
 }
 
@@ -34,14 +34,14 @@ func (t *Transcript) AppendPointsAffine(label []byte, points ...bls12381.G1Affin
 		var bytes bytes.Buffer
 		affineBytes := point.Bytes()
 		bytes.Write(affineBytes[:])
-		t.appendMessage(label, bytes.Bytes())
+		t.AppendMessage(label, bytes.Bytes())
 	}
 }
 
 func (t *Transcript) AppendScalars(label []byte, scalars ...fr.Element) {
 	for _, scalar := range scalars {
 		scalarBytes := scalar.Bytes()
-		t.appendMessage([]byte(label), scalarBytes[:])
+		t.AppendMessage([]byte(label), scalarBytes[:])
 	}
 }
 
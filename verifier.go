@@ -0,0 +1,352 @@
+package curdleproof
+
+import (
+	"fmt"
+
+	bls12381 "github.com/consensys/gnark-crypto/ecc/bls12-381"
+	"github.com/jsign/curdleproofs/common"
+	"github.com/jsign/curdleproofs/msmaccumulator"
+	"github.com/jsign/curdleproofs/samemultiscalarargument"
+	"github.com/jsign/curdleproofs/samepermutationargument"
+	"github.com/jsign/curdleproofs/samescalarargument"
+	"github.com/jsign/curdleproofs/transcript"
+)
+
+// Verifier verifies shuffle proofs against a fixed CRS. It validates the CRS and
+// computes the CRS-derived bases once, so it's cheaper than Verify when
+// verifying many proofs. A Verifier is safe for concurrent use.
+type Verifier struct {
+	// crs is the CRS given to NewVerifier padded to the size of the arguments,
+	// and size the number of Gs bases it had. MPad is added to M, see CRS.padded.
+	crs    CRS
+	size   int
+	MPad   bls12381.G1Jac
+	digest *crsDigest
+
+	// GsHs is Gs || Hs, the basis of the grand product argument.
+	GsHs []bls12381.G1Affine
+	// TsSuffix and UsSuffix are the points appended to Ts and Us in the same
+	// multiscalar argument.
+	TsSuffix []bls12381.G1Affine
+	UsSuffix []bls12381.G1Affine
+
+	// table holds Gs || Hs || H || Gt || Gu. The bases the arguments accumulate
+	// checks against are registered in it, so the MSM accumulator adds their
+	// scalars by index: Gs, Gs || Hs and Gs || Hs || H for the grand product
+	// and inner product arguments, and G = Gs || Hs[:len(Hs)-2] || Gt || Gu for
+	// the same multiscalar argument.
+	table                          *msmaccumulator.Table
+	GsBasis, GsHsBasis, GsHsHBasis *msmaccumulator.Basis
+	GBasis                         *msmaccumulator.Basis
+}
+
+// NewVerifier validates crs and precomputes the data used by Verify.
+func NewVerifier(crs CRS) (*Verifier, error) {
+	if err := crs.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid crs: %w", err)
+	}
+	return newVerifier(crs)
+}
+
+// newVerifier is like NewVerifier but only checks the shape of crs, see Verify.
+func newVerifier(crs CRS) (*Verifier, error) {
+	if err := crs.checkShape(); err != nil {
+		return nil, fmt.Errorf("invalid crs: %w", err)
+	}
+	// The digest is of the CRS as given, which also binds the padding bases.
+	original := crs
+	digest := &crsDigest{crs: &original}
+	crs, MPad, err := original.padded(digest)
+	if err != nil {
+		return nil, fmt.Errorf("padding crs: %s", err)
+	}
+
+	hgtgu := bls12381.BatchJacobianToAffineG1([]bls12381.G1Jac{crs.H, crs.Gt, crs.Gu})
+	HAffine := hgtgu[0]
+
+	G := make([]bls12381.G1Affine, 0, len(crs.Gs)+(len(crs.Hs)-2)+1+1)
+	G = append(G, crs.Gs...)
+	G = append(G, crs.Hs[:len(crs.Hs)-2]...)
+	G = append(G, hgtgu[1:]...)
+
+	points := make([]bls12381.G1Affine, 0, len(crs.Gs)+len(crs.Hs)+3)
+	points = append(points, crs.Gs...)
+	points = append(points, crs.Hs...)
+	points = append(points, hgtgu...)
+	GsHsH := points[: len(crs.Gs)+len(crs.Hs)+1 : len(crs.Gs)+len(crs.Hs)+1]
+	GsHs := GsHsH[: len(GsHsH)-1 : len(GsHsH)-1]
+
+	TsSuffix, UsSuffix := multiscalarSuffixes(len(crs.Hs), HAffine)
+
+	// Handles of Gs || Hs[:len(Hs)-2] || Gt || Gu.
+	iGt := len(GsHsH)
+	GHandles := append(handleRange(0, len(crs.Gs)+len(crs.Hs)-2), iGt, iGt+1)
+
+	v := &Verifier{
+		crs:      crs,
+		size:     len(original.Gs),
+		MPad:     MPad,
+		digest:   digest,
+		GsHs:     GsHs,
+		TsSuffix: TsSuffix,
+		UsSuffix: UsSuffix,
+		table:    msmaccumulator.NewTable(points),
+	}
+	for _, b := range []struct {
+		basis   **msmaccumulator.Basis
+		points  []bls12381.G1Affine
+		handles []int
+	}{
+		{&v.GsBasis, crs.Gs, handleRange(0, len(crs.Gs))},
+		{&v.GsHsBasis, GsHs, handleRange(0, len(GsHs))},
+		{&v.GsHsHBasis, GsHsH, handleRange(0, len(GsHsH))},
+		{&v.GBasis, G, GHandles},
+	} {
+		if *b.basis, err = v.table.Register(b.points, b.handles); err != nil {
+			return nil, fmt.Errorf("registering bases: %s", err)
+		}
+	}
+	return v, nil
+}
+
+// handleRange returns the handles [start, end).
+func handleRange(start, end int) []int {
+	handles := make([]int, end-start)
+	for i := range handles {
+		handles[i] = start + i
+	}
+	return handles
+}
+
+func (v *Verifier) Verify(
+	proof Proof,
+	Rs []bls12381.G1Affine,
+	Ss []bls12381.G1Affine,
+	Ts []bls12381.G1Affine,
+	Us []bls12381.G1Affine,
+	M bls12381.G1Jac,
+	rand common.Rand,
+	opts ...Option,
+) (bool, error) {
+	c := newConfig(opts)
+	msmAccumulator := msmaccumulator.NewWithTable(v.table)
+	ok, err := v.accumulate(proof, Instance{Rs: Rs, Ss: Ss, Ts: Ts, Us: Us, M: M}, msmAccumulator, rand, c)
+	if err != nil || !ok {
+		return false, err
+	}
+
+	ok, err = msmAccumulator.Verify(c.exec)
+	if err != nil {
+		return false, fmt.Errorf("verifying msm accumulator: %s", err)
+	}
+	return ok, nil
+}
+
+// VerifyBatch verifies many proofs sharing a single MSM accumulator, so the CRS
+// bases common to all proofs are merged into one MSM. If the batch is invalid,
+// it returns the index of the first invalid proof, falling back to verifying
+// each proof on its own if needed. Otherwise, the returned index is -1.
+func (v *Verifier) VerifyBatch(
+	instances []Instance,
+	proofs []Proof,
+	rand common.Rand,
+	opts ...Option,
+) (bool, int, error) {
+	if len(instances) != len(proofs) {
+		return false, -1, fmt.Errorf("got %d instances but %d proofs", len(instances), len(proofs))
+	}
+	c := newConfig(opts)
+
+	msmAccumulator := msmaccumulator.NewWithTable(v.table)
+	for i := range proofs {
+		ok, err := v.accumulate(proofs[i], instances[i], msmAccumulator, rand, c)
+		if err != nil {
+			return false, i, fmt.Errorf("verifying proof %d: %w", i, err)
+		}
+		if !ok {
+			return false, i, nil
+		}
+	}
+	ok, err := msmAccumulator.Verify(c.exec)
+	if err != nil {
+		return false, -1, fmt.Errorf("verifying msm accumulator: %s", err)
+	}
+	if ok {
+		return true, -1, nil
+	}
+
+	// Find the offending proof.
+	for i := range proofs {
+		in := instances[i]
+		ok, err := v.Verify(proofs[i], in.Rs, in.Ss, in.Ts, in.Us, in.M, rand, opts...)
+		if err != nil {
+			return false, i, fmt.Errorf("verifying proof %d: %w", i, err)
+		}
+		if !ok {
+			return false, i, nil
+		}
+	}
+	return false, -1, fmt.Errorf("batch is invalid but every proof is valid on its own")
+}
+
+// Diagnose runs every check of the proof on its own and returns the labels of
+// the failing ones, such as "same scalar", "same permutation B", "ipa check 1",
+// "same multiscalar T" or "R". A proof is valid iff none fails. It's slower
+// than Verify, and meant to trace why a proof, for example one generated by
+// another implementation, is rejected.
+func (v *Verifier) Diagnose(
+	proof Proof,
+	Rs []bls12381.G1Affine,
+	Ss []bls12381.G1Affine,
+	Ts []bls12381.G1Affine,
+	Us []bls12381.G1Affine,
+	M bls12381.G1Jac,
+	rand common.Rand,
+	opts ...Option,
+) ([]string, error) {
+	c := newConfig(opts)
+	var failing []string
+	c.failedChecks = &failing
+
+	msmAccumulator := msmaccumulator.NewWithTable(v.table)
+	msmAccumulator.EnableDebug()
+	if _, err := v.accumulate(proof, Instance{Rs: Rs, Ss: Ss, Ts: Ts, Us: Us, M: M}, msmAccumulator, rand, c); err != nil {
+		return nil, err
+	}
+	msmFailing, err := msmAccumulator.FailingChecks(c.exec)
+	if err != nil {
+		return nil, fmt.Errorf("checking msm accumulator checks: %w", err)
+	}
+	return append(failing, msmFailing...), nil
+}
+
+// accumulate runs every check of the proof, deferring the MSM checks to msmAccumulator.
+func (v *Verifier) accumulate(
+	proof Proof,
+	instance Instance,
+	msmAccumulator *msmaccumulator.MsmAccumulator,
+	rand common.Rand,
+	c config,
+) (bool, error) {
+	Rs, Ss, Ts, Us, M := instance.Rs, instance.Ss, instance.Ts, instance.Us, instance.M
+
+	// This also makes sure that the randomizer was not the zero element (and wiped out the ciphertexts).
+	if err := validateInstance(instance, v.size); err != nil {
+		return false, err
+	}
+
+	transcript := transcript.New(labelTranscript)
+	if err := appendHeader(transcript, v.digest, len(Rs), len(v.crs.Hs), c); err != nil {
+		return false, err
+	}
+
+	// The proof is for the instance padded to the CRS size, see Instance.pad.
+	padded := instance.pad(len(v.crs.Gs))
+	Rs, Ss, Ts, Us = padded.Rs, padded.Ss, padded.Ts, padded.Us
+	M.AddAssign(&v.MPad)
+
+	// Step 1
+	transcript.AppendPointsAffine(labelStep1, Rs...)
+	transcript.AppendPointsAffine(labelStep1, Ss...)
+	transcript.AppendPointsAffine(labelStep1, Ts...)
+	transcript.AppendPointsAffine(labelStep1, Us...)
+	transcript.AppendPoints(labelStep1, M)
+	as := transcript.GetAndAppendChallenges(labelVecA, len(Rs))
+
+	// Step 2
+	if err := c.exec.Err(); err != nil {
+		return false, err
+	}
+	ok, err := samepermutationargument.Verify(
+		proof.ProofSamePermutation,
+		samepermutationargument.CRS{
+			Gs:         v.crs.Gs,
+			Hs:         v.crs.Hs,
+			H:          v.crs.H,
+			GsHs:       v.GsHs,
+			GsBasis:    v.GsBasis,
+			GsHsBasis:  v.GsHsBasis,
+			GsHsHBasis: v.GsHsHBasis,
+		},
+		v.crs.Gsum,
+		v.crs.Hsum,
+		proof.A,
+		M,
+		as,
+		len(v.crs.Hs),
+		transcript,
+		msmAccumulator,
+		rand,
+		c.exec,
+	)
+	if err != nil {
+		return false, fmt.Errorf("verifying same permutation: %w", err)
+	}
+	if !ok {
+		return false, nil
+	}
+
+	// Step 3
+	if ok := samescalarargument.Verify(
+		proof.ProofSameScalar,
+		samescalarargument.CRS{
+			Gt: v.crs.Gt,
+			Gu: v.crs.Gu,
+			H:  v.crs.H,
+		},
+		proof.R,
+		proof.S,
+		proof.T,
+		proof.U,
+		transcript,
+	); !ok {
+		if c.failedChecks == nil {
+			return false, nil
+		}
+		*c.failedChecks = append(*c.failedChecks, "same scalar")
+	}
+
+	// Step 4
+	if err := c.exec.Err(); err != nil {
+		return false, err
+	}
+	Aprime := proof.A
+	Aprime.AddAssign(&proof.T.T_1).AddAssign(&proof.U.T_1)
+
+	Tsprime := make([]bls12381.G1Affine, 0, len(Ts)+len(v.TsSuffix))
+	Tsprime = append(Tsprime, Ts...)
+	Tsprime = append(Tsprime, v.TsSuffix...)
+
+	Usprime := make([]bls12381.G1Affine, 0, len(Us)+len(v.UsSuffix))
+	Usprime = append(Usprime, Us...)
+	Usprime = append(Usprime, v.UsSuffix...)
+
+	ok, err = samemultiscalarargument.Verify(
+		proof.ProofSameMultiscalar,
+		v.GBasis,
+		Aprime,
+		proof.T.T_2,
+		proof.U.T_2,
+		Tsprime,
+		Usprime,
+		transcript,
+		msmAccumulator,
+		rand,
+		c.exec,
+	)
+	if err != nil {
+		return false, fmt.Errorf("verifying same multiscalar: %w", err)
+	}
+	if !ok {
+		return false, nil
+	}
+
+	if err := msmAccumulator.AccumulateCheck("R", proof.R, as, msmaccumulator.NewBasis(Rs), rand); err != nil {
+		return false, fmt.Errorf("msm accumulator check R, as, Rs: %s", err)
+	}
+	if err := msmAccumulator.AccumulateCheck("S", proof.S, as, msmaccumulator.NewBasis(Ss), rand); err != nil {
+		return false, fmt.Errorf("msm accumulator check S, as, Ss: %s", err)
+	}
+
+	return true, nil
+}
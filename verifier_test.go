@@ -0,0 +1,189 @@
+package curdleproof
+
+import (
+	"fmt"
+	"sync"
+	"testing"
+
+	bls12381 "github.com/consensys/gnark-crypto/ecc/bls12-381"
+	"github.com/jsign/curdleproofs/common"
+	"github.com/stretchr/testify/require"
+)
+
+func TestVerifierConcurrent(t *testing.T) {
+	t.Parallel()
+
+	n := 64
+	crs, Rs, Ss, Ts, Us, M, perm, k, rs_m := setup(t, n)
+	proof, err := Prove(crs, Rs, Ss, Ts, Us, M, perm, k, rs_m, common.NewSecureRand())
+	require.NoError(t, err)
+
+	verifier, err := NewVerifier(crs)
+	require.NoError(t, err)
+
+	var wg sync.WaitGroup
+	oks := make([]bool, 8)
+	errs := make([]error, len(oks))
+	for i := range oks {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			// Odd goroutines verify an invalid statement (flipped Rs and Ss).
+			if i%2 == 0 {
+				oks[i], errs[i] = verifier.Verify(proof, Rs, Ss, Ts, Us, M, common.NewSecureRand())
+			} else {
+				oks[i], errs[i] = verifier.Verify(proof, Ss, Rs, Ts, Us, M, common.NewSecureRand())
+			}
+		}(i)
+	}
+	wg.Wait()
+
+	for i := range oks {
+		require.NoError(t, errs[i])
+		require.Equal(t, i%2 == 0, oks[i])
+	}
+}
+
+func BenchmarkVerifierReuse(b *testing.B) {
+	rand, err := common.NewTestingRand(42)
+	require.NoError(b, err)
+
+	for _, n := range []int{128, 512} {
+		crs, Rs, Ss, Ts, Us, M, perm, k, rs_m := setup(b, n)
+		proof, err := Prove(crs, Rs, Ss, Ts, Us, M, perm, k, rs_m, rand)
+		require.NoError(b, err)
+
+		b.Run(fmt.Sprintf("Verify/shuffled elements=%d", n-common.N_BLINDERS), func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				_, _ = Verify(proof, crs, Rs, Ss, Ts, Us, M, rand)
+			}
+		})
+		// NewVerifier validates the CRS, which one-shot Verify skips.
+		b.Run(fmt.Sprintf("NewVerifier+Verify/shuffled elements=%d", n-common.N_BLINDERS), func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				verifier, err := NewVerifier(crs)
+				require.NoError(b, err)
+				_, _ = verifier.Verify(proof, Rs, Ss, Ts, Us, M, rand)
+			}
+		})
+		b.Run(fmt.Sprintf("Verifier.Verify/shuffled elements=%d", n-common.N_BLINDERS), func(b *testing.B) {
+			verifier, err := NewVerifier(crs)
+			require.NoError(b, err)
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				_, _ = verifier.Verify(proof, Rs, Ss, Ts, Us, M, rand)
+			}
+		})
+	}
+}
+
+func TestVerifyBatch(t *testing.T) {
+	t.Parallel()
+
+	n := 64
+	crs, Rs, Ss, Ts, Us, M, perm, k, rs_m := setup(t, n)
+	instance := Instance{Rs: Rs, Ss: Ss, Ts: Ts, Us: Us, M: M}
+
+	instances := make([]Instance, 4)
+	proofs := make([]Proof, len(instances))
+	for i := range proofs {
+		var err error
+		instances[i] = instance
+		proofs[i], err = Prove(crs, Rs, Ss, Ts, Us, M, perm, k, rs_m, common.NewSecureRand())
+		require.NoError(t, err)
+	}
+
+	t.Run("valid", func(t *testing.T) {
+		ok, idx, err := VerifyBatch(crs, instances, proofs, common.NewSecureRand())
+		require.NoError(t, err)
+		require.True(t, ok)
+		require.Equal(t, -1, idx)
+	})
+
+	t.Run("invalid proof", func(t *testing.T) {
+		// Shift the outputs, so the shuffle is only caught by the final MSM.
+		invalidInstances := append([]Instance(nil), instances...)
+		invalidInstances[2].Ts = append(Ts[1:len(Ts):len(Ts)], Ts[0])
+		invalidInstances[2].Us = append(Us[1:len(Us):len(Us)], Us[0])
+
+		ok, idx, err := VerifyBatch(crs, invalidInstances, proofs, common.NewSecureRand())
+		require.NoError(t, err)
+		require.False(t, ok)
+		require.Equal(t, 2, idx)
+	})
+
+	t.Run("length mismatch", func(t *testing.T) {
+		_, _, err := VerifyBatch(crs, instances[:3], proofs, common.NewSecureRand())
+		require.Error(t, err)
+	})
+}
+
+func TestVerifyMalformedInput(t *testing.T) {
+	t.Parallel()
+
+	n := 64
+	crs, Rs, Ss, Ts, Us, M, perm, k, rs_m := setup(t, n)
+	proof, err := Prove(crs, Rs, Ss, Ts, Us, M, perm, k, rs_m, common.NewSecureRand())
+	require.NoError(t, err)
+
+	smallCRS, err := GenerateCRS(n/2-common.N_BLINDERS, common.NewSecureRand())
+	require.NoError(t, err)
+	identityTs := append([]bls12381.G1Affine(nil), Ts...)
+	identityTs[3] = bls12381.G1Affine{}
+
+	tests := []struct {
+		name        string
+		crs         CRS
+		Rs, Ss      []bls12381.G1Affine
+		Ts, Us      []bls12381.G1Affine
+		expectedErr error
+	}{
+		{"empty", crs, nil, nil, nil, nil, ErrEmptyInstance},
+		{"length mismatch", crs, Rs, Ss[1:], Ts, Us, ErrLengthMismatch},
+		{"crs size mismatch", smallCRS, Rs, Ss, Ts, Us, ErrCRSSizeMismatch},
+		{"identity point", crs, Rs, Ss, identityTs, Us, ErrIdentityPoint},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			ok, err := Verify(proof, tt.crs, tt.Rs, tt.Ss, tt.Ts, tt.Us, M, common.NewSecureRand())
+			require.ErrorIs(t, err, tt.expectedErr)
+			require.False(t, ok)
+		})
+	}
+
+	t.Run("truncated proof", func(t *testing.T) {
+		badProof := proof
+		badProof.ProofSameMultiscalar.L_T = badProof.ProofSameMultiscalar.L_T[1:]
+		ok, err := Verify(badProof, crs, Rs, Ss, Ts, Us, M, common.NewSecureRand())
+		require.ErrorIs(t, err, ErrMalformedProof)
+		require.False(t, ok)
+
+		badProof = proof
+		badProof.ProofSamePermutation.GPAProof.IPAProof.R_Ds = nil
+		ok, err = Verify(badProof, crs, Rs, Ss, Ts, Us, M, common.NewSecureRand())
+		require.ErrorIs(t, err, ErrMalformedProof)
+		require.False(t, ok)
+	})
+}
+
+// BenchmarkVerifierLarge measures verification at the sizes where computing the
+// folding scalars of the inner product and same multiscalar arguments matters.
+func BenchmarkVerifierLarge(b *testing.B) {
+	rand, err := common.NewTestingRand(42)
+	require.NoError(b, err)
+
+	for _, n := range []int{512, 4096} {
+		crs, Rs, Ss, Ts, Us, M, perm, k, rs_m := setup(b, n)
+		proof, err := Prove(crs, Rs, Ss, Ts, Us, M, perm, k, rs_m, rand)
+		require.NoError(b, err)
+		verifier, err := NewVerifier(crs)
+		require.NoError(b, err)
+
+		b.Run(fmt.Sprintf("shuffled elements=%d", n-common.N_BLINDERS), func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				_, _ = verifier.Verify(proof, Rs, Ss, Ts, Us, M, rand)
+			}
+		})
+	}
+}
@@ -0,0 +1,122 @@
+package whisk
+
+import (
+	"crypto/sha256"
+	"fmt"
+)
+
+// SSZ support for the Whisk containers:
+//
+//	class WhiskTracker(Container):
+//	    r_G: BLSG1Point  # Bytes48
+//	    k_r_G: BLSG1Point  # Bytes48
+//
+//	WhiskShuffleProof = ByteVector[WHISK_SHUFFLE_PROOF_SIZE]
+//	WhiskTrackerProof = ByteVector[WHISK_TRACKER_PROOF_SIZE]
+//
+// All of them are fixed size, so their SSZ serialization is the concatenation of
+// their fields.
+
+const bytesPerChunk = 32
+
+func (wt *WhiskTracker) SizeSSZ() int {
+	return WHISK_TRACKER_SIZE
+}
+
+func (wt *WhiskTracker) MarshalSSZ() ([]byte, error) {
+	return wt.MarshalSSZTo(make([]byte, 0, WHISK_TRACKER_SIZE))
+}
+
+// MarshalSSZTo appends the SSZ serialization of the tracker to dst.
+func (wt *WhiskTracker) MarshalSSZTo(dst []byte) ([]byte, error) {
+	dst = append(dst, wt.rG[:]...)
+	dst = append(dst, wt.krG[:]...)
+	return dst, nil
+}
+
+// UnmarshalSSZ decodes a tracker, rejecting points that aren't valid G1 elements.
+func (wt *WhiskTracker) UnmarshalSSZ(buf []byte) error {
+	if len(buf) != WHISK_TRACKER_SIZE {
+		return fmt.Errorf("tracker must have %d bytes, got %d", WHISK_TRACKER_SIZE, len(buf))
+	}
+	var tmp WhiskTracker
+	copy(tmp.rG[:], buf[:G1POINT_SIZE])
+	copy(tmp.krG[:], buf[G1POINT_SIZE:])
+	if _, _, err := tmp.getPoints(); err != nil {
+		return err
+	}
+	*wt = tmp
+	return nil
+}
+
+func (wt *WhiskTracker) HashTreeRoot() ([32]byte, error) {
+	rGRoot := merkleizeBytes(wt.rG[:])
+	krGRoot := merkleizeBytes(wt.krG[:])
+	return merkleizeBytes(append(rGRoot[:], krGRoot[:]...)), nil
+}
+
+func (b *WhiskShuffleProofBytes) SizeSSZ() int {
+	return WHISK_SHUFFLE_PROOF_SIZE
+}
+
+func (b *WhiskShuffleProofBytes) MarshalSSZ() ([]byte, error) {
+	return append([]byte(nil), b[:]...), nil
+}
+
+func (b *WhiskShuffleProofBytes) UnmarshalSSZ(buf []byte) error {
+	if len(buf) != WHISK_SHUFFLE_PROOF_SIZE {
+		return fmt.Errorf("shuffle proof must have %d bytes, got %d", WHISK_SHUFFLE_PROOF_SIZE, len(buf))
+	}
+	copy(b[:], buf)
+	return nil
+}
+
+func (b *WhiskShuffleProofBytes) HashTreeRoot() ([32]byte, error) {
+	return merkleizeBytes(b[:]), nil
+}
+
+func (b *TrackerProofBytes) SizeSSZ() int {
+	return TRACKER_PROOF_SIZE
+}
+
+func (b *TrackerProofBytes) MarshalSSZ() ([]byte, error) {
+	return append([]byte(nil), b[:]...), nil
+}
+
+func (b *TrackerProofBytes) UnmarshalSSZ(buf []byte) error {
+	if len(buf) != TRACKER_PROOF_SIZE {
+		return fmt.Errorf("tracker proof must have %d bytes, got %d", TRACKER_PROOF_SIZE, len(buf))
+	}
+	copy(b[:], buf)
+	return nil
+}
+
+func (b *TrackerProofBytes) HashTreeRoot() ([32]byte, error) {
+	return merkleizeBytes(b[:]), nil
+}
+
+// merkleizeBytes packs data into 32-byte chunks, right-padding the last one with
+// zeros, and returns the root of the Merkle tree over them after padding the number
+// of chunks to the next power of two.
+func merkleizeBytes(data []byte) [32]byte {
+	nbChunks := (len(data) + bytesPerChunk - 1) / bytesPerChunk
+	width := 1
+	for width < nbChunks {
+		width <<= 1
+	}
+
+	layer := make([][32]byte, width)
+	for i := 0; i < nbChunks; i++ {
+		copy(layer[i][:], data[i*bytesPerChunk:])
+	}
+	for len(layer) > 1 {
+		var pair [2 * bytesPerChunk]byte
+		for i := 0; i < len(layer)/2; i++ {
+			copy(pair[:], layer[2*i][:])
+			copy(pair[bytesPerChunk:], layer[2*i+1][:])
+			layer[i] = sha256.Sum256(pair[:])
+		}
+		layer = layer[:len(layer)/2]
+	}
+	return layer[0]
+}
@@ -0,0 +1,137 @@
+package whisk
+
+import (
+	"crypto/sha256"
+	"encoding"
+	"encoding/json"
+	"testing"
+
+	"github.com/consensys/gnark-crypto/ecc/bls12-381/fr"
+	"github.com/jsign/curdleproofs/common"
+	"github.com/stretchr/testify/require"
+)
+
+var (
+	_ encoding.BinaryMarshaler   = (*WhiskTracker)(nil)
+	_ encoding.BinaryUnmarshaler = (*WhiskTracker)(nil)
+	_ encoding.BinaryMarshaler   = (*WhiskShuffleProof)(nil)
+	_ encoding.BinaryUnmarshaler = (*WhiskShuffleProof)(nil)
+	_ encoding.BinaryMarshaler   = (*TrackerProof)(nil)
+	_ encoding.BinaryUnmarshaler = (*TrackerProof)(nil)
+)
+
+func TestWhiskTrackerSSZ(t *testing.T) {
+	t.Parallel()
+
+	rand, err := common.NewTestingRand(0)
+	require.NoError(t, err)
+	tracker := generateTracker(t, rand, fr.NewElement(42))
+
+	encoded, err := tracker.MarshalSSZ()
+	require.NoError(t, err)
+	require.Len(t, encoded, tracker.SizeSSZ())
+	require.Equal(t, tracker.rG[:], encoded[:G1POINT_SIZE])
+	require.Equal(t, tracker.krG[:], encoded[G1POINT_SIZE:])
+
+	var decoded WhiskTracker
+	require.NoError(t, decoded.UnmarshalSSZ(encoded))
+	require.Equal(t, tracker, decoded)
+
+	binary, err := tracker.MarshalBinary()
+	require.NoError(t, err)
+	require.Equal(t, encoded, binary)
+
+	// hash_tree_root(Bytes48) merkleizes two chunks, the second one zero padded.
+	bytes48Root := func(b G1PointBytes) [32]byte {
+		var chunks [64]byte
+		copy(chunks[:], b[:])
+		return sha256.Sum256(chunks[:])
+	}
+	rGRoot, krGRoot := bytes48Root(tracker.rG), bytes48Root(tracker.krG)
+	expected := sha256.Sum256(append(rGRoot[:], krGRoot[:]...))
+	root, err := tracker.HashTreeRoot()
+	require.NoError(t, err)
+	require.Equal(t, expected, root)
+
+	require.Error(t, decoded.UnmarshalSSZ(encoded[1:]))
+	encoded[0] ^= 0x01
+	require.Error(t, decoded.UnmarshalSSZ(encoded))
+}
+
+func TestByteVectorsSSZ(t *testing.T) {
+	t.Parallel()
+
+	// zeroHashes[i] is the root of a tree of depth i with zero leaves.
+	zeroHashes := make([][32]byte, 9)
+	for i := 1; i < len(zeroHashes); i++ {
+		zeroHashes[i] = sha256.Sum256(append(zeroHashes[i-1][:], zeroHashes[i-1][:]...))
+	}
+
+	// WHISK_SHUFFLE_PROOF_SIZE is 143 chunks, padded to 256.
+	var proof WhiskShuffleProofBytes
+	root, err := proof.HashTreeRoot()
+	require.NoError(t, err)
+	require.Equal(t, zeroHashes[8], root)
+
+	// TRACKER_PROOF_SIZE is 4 chunks.
+	var trackerProof TrackerProofBytes
+	root, err = trackerProof.HashTreeRoot()
+	require.NoError(t, err)
+	require.Equal(t, zeroHashes[2], root)
+
+	trackerProof[0] = 1
+	encoded, err := trackerProof.MarshalSSZ()
+	require.NoError(t, err)
+	var decoded TrackerProofBytes
+	require.NoError(t, decoded.UnmarshalSSZ(encoded))
+	require.Equal(t, trackerProof, decoded)
+	require.Error(t, decoded.UnmarshalSSZ(encoded[1:]))
+
+	var chunk0 [32]byte
+	chunk0[0] = 1
+	left := sha256.Sum256(append(chunk0[:], zeroHashes[0][:]...))
+	expected := sha256.Sum256(append(left[:], zeroHashes[1][:]...))
+	root, err = trackerProof.HashTreeRoot()
+	require.NoError(t, err)
+	require.Equal(t, expected, root)
+}
+
+func TestProofsBinaryMarshaling(t *testing.T) {
+	t.Parallel()
+
+	rand, err := common.NewTestingRand(0)
+	require.NoError(t, err)
+
+	k, err := rand.GetFr()
+	require.NoError(t, err)
+	tracker := generateTracker(t, rand, k)
+	trackerProofBytes, err := GenerateWhiskTrackerProof(tracker, k, rand)
+	require.NoError(t, err)
+
+	var trackerProof TrackerProof
+	require.NoError(t, trackerProof.UnmarshalBinary(trackerProofBytes[:]))
+	encoded, err := trackerProof.MarshalBinary()
+	require.NoError(t, err)
+	require.Equal(t, trackerProofBytes[:], encoded)
+	require.Error(t, trackerProof.UnmarshalBinary(encoded[1:]))
+}
+
+func TestWhiskTrackerJSON(t *testing.T) {
+	t.Parallel()
+
+	rand, err := common.NewTestingRand(0)
+	require.NoError(t, err)
+	tracker := generateTracker(t, rand, fr.NewElement(42))
+
+	encoded, err := json.Marshal(tracker)
+	require.NoError(t, err)
+	rG, krG, err := tracker.getPoints()
+	require.NoError(t, err)
+	require.JSONEq(t, `{"r_G":"`+common.G1AffineToHex(&rG)+`","k_r_G":"`+common.G1AffineToHex(&krG)+`"}`, string(encoded))
+
+	var decoded WhiskTracker
+	require.NoError(t, json.Unmarshal(encoded, &decoded))
+	require.Equal(t, tracker, decoded)
+
+	require.Error(t, json.Unmarshal([]byte(`{"r_G":"0x00","k_r_G":"0x00"}`), &decoded))
+}
@@ -2,6 +2,8 @@ package whisk
 
 import (
 	"bytes"
+	"encoding/hex"
+	"encoding/json"
 	"fmt"
 	"io"
 
@@ -17,7 +19,13 @@ const (
 	N   = 128
 	ELL = N - common.N_BLINDERS
 
-	TRACKER_PROOF_SIZE       = 128
+	WHISK_TRACKER_SIZE = 2 * G1POINT_SIZE
+	// TRACKER_PROOF_SIZE is the size of A, B and s.
+	TRACKER_PROOF_SIZE = 2*G1POINT_SIZE + fr.Bytes
+	// WHISK_SHUFFLE_PROOF_SIZE is the size fixed by the spec. The reference
+	// implementation prefixes vectors with 8-byte lengths while this encoding
+	// uses 4-byte ones, so M and the proof take shuffleProofSize() bytes and the
+	// rest is zero-padded.
 	WHISK_SHUFFLE_PROOF_SIZE = 4576
 )
 
@@ -36,21 +44,79 @@ type WhiskShuffleProof struct {
 	Proof curdleproof.Proof
 }
 
+// shuffleProofSize returns the size of the encoding of M and a proof for ELL
+// elements, derived from the structure of the proof.
+func shuffleProofSize() (int, error) {
+	proofSize, err := curdleproof.ProofSize(ELL, common.N_BLINDERS)
+	if err != nil {
+		return 0, fmt.Errorf("computing proof size: %s", err)
+	}
+	size := G1POINT_SIZE + proofSize
+	if size > WHISK_SHUFFLE_PROOF_SIZE {
+		return 0, fmt.Errorf("shuffle proof has %d bytes but WHISK_SHUFFLE_PROOF_SIZE is %d", size, WHISK_SHUFFLE_PROOF_SIZE)
+	}
+	return size, nil
+}
+
 func (wsp *WhiskShuffleProof) FromReader(r io.Reader) error {
 	// TODO(jsign): revisit since "decoder" for single element is overkill
 	d := bls12381.NewDecoder(r)
 	var tmp bls12381.G1Affine
 	if err := d.Decode(&tmp); err != nil {
-		return fmt.Errorf("failed to decode M: %v", err)
+		return fmt.Errorf("%w: failed to decode M: %v", curdleproof.ErrMalformedProof, err)
 	}
 	wsp.M.FromAffine(&tmp)
 	if err := wsp.Proof.FromReader(r); err != nil {
-		return fmt.Errorf("failed to decode proof: %v", err)
+		return fmt.Errorf("failed to decode proof: %w", err)
+	}
+	return nil
+}
+
+// FromBytesStrict decodes a shuffle proof accepting only its canonical encoding,
+// which includes the unused tail of the buffer being zeroed.
+func (wsp *WhiskShuffleProof) FromBytesStrict(buf WhiskShuffleProofBytes) error {
+	size, err := shuffleProofSize()
+	if err != nil {
+		return err
+	}
+	r := bytes.NewReader(buf[:size])
+	if err := common.NewStrictDecoder(r).DecodeG1Jac(&wsp.M); err != nil {
+		return fmt.Errorf("%w: failed to decode M: %v", curdleproof.ErrMalformedProof, err)
+	}
+	if err := wsp.Proof.FromReaderStrict(r, ELL, common.N_BLINDERS); err != nil {
+		return fmt.Errorf("failed to decode proof: %w", err)
+	}
+	for _, b := range buf[size:] {
+		if b != 0 {
+			return fmt.Errorf("%w: non-zero trailing data", curdleproof.ErrMalformedProof)
+		}
 	}
 	return nil
 }
 
+// MarshalBinary implements encoding.BinaryMarshaler, returning the fixed size
+// WHISK_SHUFFLE_PROOF_SIZE encoding.
+func (wsp *WhiskShuffleProof) MarshalBinary() ([]byte, error) {
+	buf, err := wsp.Serialize()
+	if err != nil {
+		return nil, err
+	}
+	return buf[:], nil
+}
+
+// UnmarshalBinary implements encoding.BinaryUnmarshaler with strict decoding.
+func (wsp *WhiskShuffleProof) UnmarshalBinary(data []byte) error {
+	if len(data) != WHISK_SHUFFLE_PROOF_SIZE {
+		return fmt.Errorf("%w: shuffle proof must have %d bytes, got %d", curdleproof.ErrMalformedProof, WHISK_SHUFFLE_PROOF_SIZE, len(data))
+	}
+	return wsp.FromBytesStrict(WhiskShuffleProofBytes(data))
+}
+
 func (wsp *WhiskShuffleProof) Serialize() (WhiskShuffleProofBytes, error) {
+	size, err := shuffleProofSize()
+	if err != nil {
+		return WhiskShuffleProofBytes{}, err
+	}
 	buf := bytes.NewBuffer(make([]byte, 0, WHISK_SHUFFLE_PROOF_SIZE))
 	e := bls12381.NewEncoder(buf)
 
@@ -64,9 +130,11 @@ func (wsp *WhiskShuffleProof) Serialize() (WhiskShuffleProofBytes, error) {
 		return WhiskShuffleProofBytes{}, fmt.Errorf("failed to encode proof: %v", err)
 	}
 
-	proofBytes := buf.Bytes()
+	if buf.Len() != size {
+		return WhiskShuffleProofBytes{}, fmt.Errorf("encoded shuffle proof has %d bytes but expected %d", buf.Len(), size)
+	}
 	var ret WhiskShuffleProofBytes
-	copy(ret[:], proofBytes)
+	copy(ret[:], buf.Bytes())
 
 	return ret, nil
 }
@@ -83,6 +151,47 @@ func NewWhiskTracker(rG, krG bls12381.G1Affine) WhiskTracker {
 	}
 }
 
+// MarshalBinary implements encoding.BinaryMarshaler; it matches the SSZ encoding.
+func (wt *WhiskTracker) MarshalBinary() ([]byte, error) {
+	return wt.MarshalSSZ()
+}
+
+// UnmarshalBinary implements encoding.BinaryUnmarshaler; it matches the SSZ encoding.
+func (wt *WhiskTracker) UnmarshalBinary(data []byte) error {
+	return wt.UnmarshalSSZ(data)
+}
+
+type whiskTrackerJSON struct {
+	RG  string `json:"r_G"`
+	KRG string `json:"k_r_G"`
+}
+
+// MarshalJSON encodes the tracker with 0x-prefixed compressed points.
+func (wt WhiskTracker) MarshalJSON() ([]byte, error) {
+	return json.Marshal(whiskTrackerJSON{
+		RG:  "0x" + hex.EncodeToString(wt.rG[:]),
+		KRG: "0x" + hex.EncodeToString(wt.krG[:]),
+	})
+}
+
+// UnmarshalJSON decodes a tracker, rejecting points that aren't valid G1 elements.
+func (wt *WhiskTracker) UnmarshalJSON(data []byte) error {
+	var wtj whiskTrackerJSON
+	if err := json.Unmarshal(data, &wtj); err != nil {
+		return err
+	}
+	rG, err := common.G1AffineFromHex(wtj.RG)
+	if err != nil {
+		return fmt.Errorf("decoding r_G: %s", err)
+	}
+	krG, err := common.G1AffineFromHex(wtj.KRG)
+	if err != nil {
+		return fmt.Errorf("decoding k_r_G: %s", err)
+	}
+	*wt = NewWhiskTracker(rG, krG)
+	return nil
+}
+
 func (wt *WhiskTracker) getPoints() (bls12381.G1Affine, bls12381.G1Affine, error) {
 	var rG, krG bls12381.G1Affine
 	if _, err := rG.SetBytes(wt.rG[:]); err != nil {
@@ -102,20 +211,39 @@ type TrackerProof struct {
 	S fr.Element
 }
 
+// FromBytes decodes a tracker proof accepting only its canonical encoding.
 func (tp *TrackerProof) FromBytes(buf TrackerProofBytes) error {
-	d := bls12381.NewDecoder(bytes.NewReader(buf[:]))
-	if err := d.Decode(&tp.A); err != nil {
-		return fmt.Errorf("failed to decode A: %v", err)
+	r := bytes.NewReader(buf[:])
+	d := common.NewStrictDecoder(r)
+	if err := d.DecodeG1Affine(&tp.A); err != nil {
+		return fmt.Errorf("%w: failed to decode A: %v", curdleproof.ErrMalformedProof, err)
 	}
-	if err := d.Decode(&tp.B); err != nil {
-		return fmt.Errorf("failed to decode B: %v", err)
+	if err := d.DecodeG1Affine(&tp.B); err != nil {
+		return fmt.Errorf("%w: failed to decode B: %v", curdleproof.ErrMalformedProof, err)
 	}
-	if err := d.Decode(&tp.S); err != nil {
-		return fmt.Errorf("failed to decode s: %v", err)
+	if err := d.DecodeFr(&tp.S); err != nil {
+		return fmt.Errorf("%w: failed to decode s: %v", curdleproof.ErrMalformedProof, err)
+	}
+	if r.Len() != 0 {
+		return fmt.Errorf("%w: %d bytes of trailing data", curdleproof.ErrMalformedProof, r.Len())
 	}
 	return nil
 }
 
+// MarshalBinary implements encoding.BinaryMarshaler.
+func (tp *TrackerProof) MarshalBinary() ([]byte, error) {
+	buf := tp.Serialize()
+	return buf[:], nil
+}
+
+// UnmarshalBinary implements encoding.BinaryUnmarshaler with strict decoding.
+func (tp *TrackerProof) UnmarshalBinary(data []byte) error {
+	if len(data) != TRACKER_PROOF_SIZE {
+		return fmt.Errorf("%w: tracker proof must have %d bytes, got %d", curdleproof.ErrMalformedProof, TRACKER_PROOF_SIZE, len(data))
+	}
+	return tp.FromBytes(TrackerProofBytes(data))
+}
+
 func (tp *TrackerProof) Serialize() TrackerProofBytes {
 	buf := bytes.NewBuffer(make([]byte, 0, TRACKER_PROOF_SIZE))
 	e := bls12381.NewEncoder(buf)
@@ -1,7 +1,6 @@
 package whisk
 
 import (
-	"bytes"
 	"fmt"
 
 	bls12381 "github.com/consensys/gnark-crypto/ecc/bls12-381"
@@ -17,14 +16,14 @@ var (
 	labelTrackerOpeningProofChallenge = []byte("tracker_opening_proof_challenge")
 )
 
-func IsValidWhiskShuffleProof(crs CRS, preST, postST []WhiskTracker, proof WhiskShuffleProofBytes, rand *common.Rand) (bool, error) {
+func IsValidWhiskShuffleProof(crs CRS, preST, postST []WhiskTracker, proof WhiskShuffleProofBytes, rand common.Rand, opts ...curdleproof.Option) (bool, error) {
 	if len(preST) != len(postST) {
 		return false, fmt.Errorf("pre and post shuffle trackers must be the same length")
 	}
 
 	var whiskProof WhiskShuffleProof
-	if err := whiskProof.FromReader(bytes.NewReader(proof[:])); err != nil {
-		return false, fmt.Errorf("decoding proof: %s", err)
+	if err := whiskProof.FromBytesStrict(proof); err != nil {
+		return false, fmt.Errorf("decoding proof: %w", err)
 	}
 
 	var err error
@@ -52,15 +51,23 @@ func IsValidWhiskShuffleProof(crs CRS, preST, postST []WhiskTracker, proof Whisk
 		Us,
 		whiskProof.M,
 		rand,
+		opts...,
 	)
 	if err != nil {
-		return false, fmt.Errorf("verifying proof: %s", err)
+		return false, fmt.Errorf("verifying proof: %w", err)
 	}
 
 	return ok, nil
 }
 
-func GenerateWhiskShuffleProof(crs CRS, preTrackers []WhiskTracker, rand *common.Rand) ([]WhiskTracker, WhiskShuffleProofBytes, error) {
+// GenerateWhiskShuffleProof shuffles and rerandomizes preTrackers, returning the
+// post-shuffle trackers and a proof of the shuffle. The permutation, the
+// rerandomization scalar and the blinders are drawn from rand, or from
+// common.NewSecureRand if rand is nil.
+func GenerateWhiskShuffleProof(crs CRS, preTrackers []WhiskTracker, rand common.Rand, opts ...curdleproof.Option) ([]WhiskTracker, WhiskShuffleProofBytes, error) {
+	if rand == nil {
+		rand = common.NewSecureRand()
+	}
 	permutation, err := rand.GeneratePermutation(ELL)
 	if err != nil {
 		return nil, WhiskShuffleProofBytes{}, fmt.Errorf("generating permutation: %s", err)
@@ -79,7 +86,7 @@ func GenerateWhiskShuffleProof(crs CRS, preTrackers []WhiskTracker, rand *common
 		}
 	}
 
-	Ts, Us, M, rs_m, err := common.ShufflePermuteCommit(crs.Gs, crs.Hs, Rs, Ss, permutation, k, rand)
+	Ts, Us, M, rs_m, err := common.ShufflePermuteCommit(crs.Gs, crs.Hs, Rs, Ss, permutation, k, rand, curdleproof.NewExec(opts...))
 	if err != nil {
 		return nil, WhiskShuffleProofBytes{}, fmt.Errorf("shuffling and permuting: %s", err)
 	}
@@ -94,9 +101,10 @@ func GenerateWhiskShuffleProof(crs CRS, preTrackers []WhiskTracker, rand *common
 		permutation,
 		k,
 		rs_m,
-		rand)
+		rand,
+		opts...)
 	if err != nil {
-		return nil, WhiskShuffleProofBytes{}, fmt.Errorf("generating proof: %s", err)
+		return nil, WhiskShuffleProofBytes{}, fmt.Errorf("generating proof: %w", err)
 	}
 
 	whiskProof := WhiskShuffleProof{M: M, Proof: proof}
@@ -116,7 +124,7 @@ func GenerateWhiskShuffleProof(crs CRS, preTrackers []WhiskTracker, rand *common
 func IsValidWhiskTrackerProof(tracker WhiskTracker, kComm G1PointBytes, trackerProofBytes TrackerProofBytes) (bool, error) {
 	var trackerProof TrackerProof
 	if err := trackerProof.FromBytes(trackerProofBytes); err != nil {
-		return false, fmt.Errorf("decoding proof: %s", err)
+		return false, fmt.Errorf("decoding proof: %w", err)
 	}
 
 	rG, krG, err := tracker.getPoints()
@@ -146,11 +154,21 @@ func IsValidWhiskTrackerProof(tracker WhiskTracker, kComm G1PointBytes, trackerP
 	return A_prime.Equal(&trackerProof.A) && B_prime.Equal(&trackerProof.B), nil
 }
 
-func GenerateWhiskTrackerProof(tracker WhiskTracker, k fr.Element, rand *common.Rand) (TrackerProofBytes, error) {
+// GenerateWhiskTrackerProof proves knowledge of the k of tracker. The blinder is
+// drawn from rand or, if rand is nil, from common.NewHedgedRand with k as the
+// witness, so it stays secret even if the operating system CSPRNG is weak.
+func GenerateWhiskTrackerProof(tracker WhiskTracker, k fr.Element, rand common.Rand) (TrackerProofBytes, error) {
 	rG, krG, err := tracker.getPoints()
 	if err != nil {
 		return TrackerProofBytes{}, fmt.Errorf("deserializing rG and krG: %s", err)
 	}
+	if rand == nil {
+		kBytes := k.Bytes()
+		trackerBytes := append(tracker.rG[:], tracker.krG[:]...)
+		if rand, err = common.NewHedgedRand(kBytes[:], trackerBytes); err != nil {
+			return TrackerProofBytes{}, fmt.Errorf("creating hedged rand: %s", err)
+		}
+	}
 
 	var kG bls12381.G1Affine
 	kG.ScalarMultiplication(&g1Gen, common.FrToBigInt(&k))
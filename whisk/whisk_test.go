@@ -1,6 +1,8 @@
 package whisk
 
 import (
+	"bytes"
+	"context"
 	"testing"
 
 	bls12381 "github.com/consensys/gnark-crypto/ecc/bls12-381"
@@ -13,7 +15,7 @@ import (
 func TestWhiskTrackerProof(t *testing.T) {
 	t.Parallel()
 
-	rand, err := common.NewRand(0)
+	rand, err := common.NewTestingRand(0)
 	require.NoError(t, err)
 
 	k, err := rand.GetFr()
@@ -27,14 +29,36 @@ func TestWhiskTrackerProof(t *testing.T) {
 	require.NoError(t, err)
 	require.True(t, ok)
 
+	// A nil rand defaults to a hedged one.
+	hedgedProof, err := GenerateWhiskTrackerProof(tracker, k, nil)
+	require.NoError(t, err)
+	ok, err = IsValidWhiskTrackerProof(tracker, kComm, hedgedProof)
+	require.NoError(t, err)
+	require.True(t, ok)
+
+	// Non-canonical encodings are rejected.
+	uncompressed := trackerProof
+	uncompressed[0] &^= 0x80
+	require.ErrorContains(t, new(TrackerProof).FromBytes(uncompressed), "failed to decode A")
+	unreduced := trackerProof
+	copy(unreduced[2*G1POINT_SIZE:], bytes.Repeat([]byte{0xff}, fr.Bytes))
+	require.ErrorContains(t, new(TrackerProof).FromBytes(unreduced), "failed to decode s")
+	_, err = IsValidWhiskTrackerProof(tracker, kComm, unreduced)
+	require.ErrorIs(t, err, curdleproof.ErrMalformedProof)
+
 	// Assert correct TRACKER_PROOF_SIZE
-	// Note: this part of the reference test isn't implemented since
-	//       in this implementation the serialized proof is forced to
-	//       be an array of length equal TRACKER_PROOF_SIZE.
+	var encoded bytes.Buffer
+	e := bls12381.NewEncoder(&encoded)
+	var decoded TrackerProof
+	require.NoError(t, decoded.FromBytes(trackerProof))
+	require.NoError(t, e.Encode(&decoded.A))
+	require.NoError(t, e.Encode(&decoded.B))
+	require.NoError(t, e.Encode(&decoded.S))
+	require.Equal(t, TRACKER_PROOF_SIZE, encoded.Len())
 }
 
 func TestWhiskShuffleProof(t *testing.T) {
-	rand, err := common.NewRand(0)
+	rand, err := common.NewTestingRand(0)
 	require.NoError(t, err)
 
 	crs, err := curdleproof.GenerateCRS(ELL, rand)
@@ -49,14 +73,45 @@ func TestWhiskShuffleProof(t *testing.T) {
 	require.NoError(t, err)
 	require.True(t, ok)
 
+	ok, err = IsValidWhiskShuffleProof(crs, shuffledTrackers, postTrackers, proofBytes, rand, curdleproof.WithNbTasks(1))
+	require.NoError(t, err)
+	require.True(t, ok)
+
+	// A nil rand defaults to the operating system CSPRNG.
+	securePostTrackers, secureProofBytes, err := GenerateWhiskShuffleProof(crs, shuffledTrackers, nil)
+	require.NoError(t, err)
+	ok, err = IsValidWhiskShuffleProof(crs, shuffledTrackers, securePostTrackers, secureProofBytes, rand)
+	require.NoError(t, err)
+	require.True(t, ok)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, _, err = GenerateWhiskShuffleProof(crs, shuffledTrackers, rand, curdleproof.WithContext(ctx))
+	require.ErrorIs(t, err, context.Canceled)
+
 	// Assert correct WHISK_SHUFFLE_PROOF_SIZE
-	// Note: this part of the reference test isn't implemented since
-	//       in this implementation the serialized proof is forced to
-	//       be an array of length equal WHISK_SHUFFLE_PROOF_SIZE.
+	var proof WhiskShuffleProof
+	require.NoError(t, proof.FromBytesStrict(proofBytes))
+	proofSize, err := curdleproof.ProofSize(ELL, common.N_BLINDERS)
+	require.NoError(t, err)
+	var encoded bytes.Buffer
+	require.NoError(t, proof.Proof.Serialize(&encoded))
+	require.Equal(t, proofSize, encoded.Len())
+	size, err := shuffleProofSize()
+	require.NoError(t, err)
+	require.Equal(t, G1POINT_SIZE+proofSize, size)
+	// The reference implementation's 10 vectors have 8-byte length prefixes
+	// instead of 4-byte ones, which is the padding up to the spec size.
+	require.Equal(t, WHISK_SHUFFLE_PROOF_SIZE, size+10*(8-common.SliceLenSize))
+
+	// A proof whose encoding has an unexpected size isn't serialized.
+	proof.Proof.ProofSameMultiscalar.L_A = proof.Proof.ProofSameMultiscalar.L_A[1:]
+	_, err = proof.Serialize()
+	require.ErrorContains(t, err, "expected")
 }
 
 func TestWhiskFullLifecycle(t *testing.T) {
-	rand, err := common.NewRand(0)
+	rand, err := common.NewTestingRand(0)
 	require.NoError(t, err)
 	crs, err := curdleproof.GenerateCRS(ELL, rand)
 	require.NoError(t, err)
@@ -89,7 +144,7 @@ func TestWhiskFullLifecycle(t *testing.T) {
 	processBlock(t, crs, &state, block1)
 }
 
-func generateTracker(t *testing.T, rand *common.Rand, k fr.Element) WhiskTracker {
+func generateTracker(t *testing.T, rand common.Rand, k fr.Element) WhiskTracker {
 	r, err := rand.GetFr()
 	require.NoError(t, err)
 	return computeTracker(k, r)
@@ -108,7 +163,7 @@ func getKComm(k fr.Element) G1PointBytes {
 	return res.ScalarMultiplication(&g1Gen, common.FrToBigInt(&k)).Bytes()
 }
 
-func generateShuffleTrackers(t *testing.T, rand *common.Rand) []WhiskTracker {
+func generateShuffleTrackers(t *testing.T, rand common.Rand) []WhiskTracker {
 	wts := make([]WhiskTracker, ELL)
 	for i := 0; i < ELL; i++ {
 		k, err := rand.GetFr()
@@ -135,7 +190,7 @@ type State struct {
 }
 
 func processBlock(t *testing.T, crs curdleproof.CRS, state *State, block *Block) {
-	rand, err := common.NewRand(0)
+	rand, err := common.NewTestingRand(0)
 	require.NoError(t, err)
 
 	// process_whisk_opening_proof
@@ -162,7 +217,7 @@ func processBlock(t *testing.T, crs curdleproof.CRS, state *State, block *Block)
 }
 
 func produceBlock(t *testing.T, crs curdleproof.CRS, state *State, proposerK fr.Element, proposerIndex uint64) *Block {
-	rand, err := common.NewRand(0)
+	rand, err := common.NewTestingRand(0)
 	require.NoError(t, err)
 
 	whiskPostShuffleTrackers, whiskShuffleProof, err := GenerateWhiskShuffleProof(crs, state.shuffledTrackers, rand)
@@ -207,3 +262,27 @@ func produceBlock(t *testing.T, crs curdleproof.CRS, state *State, proposerK fr.
 		whiskKCommitment,
 	}
 }
+
+func TestWhiskShuffleProofStrictDecoding(t *testing.T) {
+	rand, err := common.NewTestingRand(0)
+	require.NoError(t, err)
+	crs, err := curdleproof.GenerateCRS(ELL, rand)
+	require.NoError(t, err)
+
+	shuffledTrackers := generateShuffleTrackers(t, rand)
+	postTrackers, proofBytes, err := GenerateWhiskShuffleProof(crs, shuffledTrackers, rand)
+	require.NoError(t, err)
+
+	var wsp WhiskShuffleProof
+	require.NoError(t, wsp.FromBytesStrict(proofBytes))
+	encoded, err := wsp.MarshalBinary()
+	require.NoError(t, err)
+	require.Equal(t, proofBytes[:], encoded)
+	require.NoError(t, wsp.UnmarshalBinary(encoded))
+
+	// Garbage in the zero padding at the end of the buffer must be rejected.
+	proofBytes[WHISK_SHUFFLE_PROOF_SIZE-1] = 1
+	require.ErrorIs(t, wsp.FromBytesStrict(proofBytes), curdleproof.ErrMalformedProof)
+	_, err = IsValidWhiskShuffleProof(crs, shuffledTrackers, postTrackers, proofBytes, rand)
+	require.ErrorIs(t, err, curdleproof.ErrMalformedProof)
+}